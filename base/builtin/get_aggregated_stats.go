@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/process"
@@ -26,10 +27,10 @@ func init() {
 		agent: agent,
 	}
 
-	pm.CmdMap[cmdGetAggregatedStats] = process.NewInternalProcessFactory(mgr.getAggregatedStats)
+	pm.RegisterBuiltIn(cmdGetAggregatedStats, mgr.getAggregatedStats)
 }
 
-func (mgr *aggregatedStatsMgr) getAggregatedStats(cmd *core.Command) (interface{}, error) {
+func (mgr *aggregatedStatsMgr) getAggregatedStats(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	stat := process.ProcessStats{}
 
 	for _, runner := range pm.GetManager().Runners() {
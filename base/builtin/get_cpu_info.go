@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/shirou/gopsutil/cpu"
 )
 
@@ -12,9 +12,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetCPUInfo] = process.NewInternalProcessFactory(getCPUInfo)
+	pm.RegisterBuiltIn(cmdGetCPUInfo, getCPUInfo)
 }
 
-func getCPUInfo(cmd *core.Command) (interface{}, error) {
+func getCPUInfo(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return cpu.Info()
 }
@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/shirou/gopsutil/disk"
 )
 
@@ -12,9 +12,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetDiskInfo] = process.NewInternalProcessFactory(getDiskInfo)
+	pm.RegisterBuiltIn(cmdGetDiskInfo, getDiskInfo)
 }
 
-func getDiskInfo(cmd *core.Command) (interface{}, error) {
+func getDiskInfo(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return disk.Partitions(true)
 }
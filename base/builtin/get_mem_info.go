@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/shirou/gopsutil/mem"
 )
 
@@ -12,9 +12,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetMemInfo] = process.NewInternalProcessFactory(getMemInfo)
+	pm.RegisterBuiltIn(cmdGetMemInfo, getMemInfo)
 }
 
-func getMemInfo(cmd *core.Command) (interface{}, error) {
+func getMemInfo(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return mem.VirtualMemory()
 }
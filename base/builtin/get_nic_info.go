@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/shirou/gopsutil/net"
 )
 
@@ -12,9 +12,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetNicInfo] = process.NewInternalProcessFactory(getNicInfo)
+	pm.RegisterBuiltIn(cmdGetNicInfo, getNicInfo)
 }
 
-func getNicInfo(cmd *core.Command) (interface{}, error) {
+func getNicInfo(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return net.Interfaces()
 }
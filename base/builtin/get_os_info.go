@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/shirou/gopsutil/host"
 )
 
@@ -12,9 +12,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetOsInfo] = process.NewInternalProcessFactory(getOsInfo)
+	pm.RegisterBuiltIn(cmdGetOsInfo, getOsInfo)
 }
 
-func getOsInfo(cmd *core.Command) (interface{}, error) {
+func getOsInfo(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return host.Info()
 }
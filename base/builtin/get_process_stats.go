@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/g8os/core0/base/pm"
@@ -13,14 +14,14 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdGetProcessStats] = process.NewInternalProcessFactory(getProcessStats)
+	pm.RegisterBuiltIn(cmdGetProcessStats, getProcessStats)
 }
 
 type getProcessStatsData struct {
 	ID string `json:"id"`
 }
 
-func getProcessStats(cmd *core.Command) (interface{}, error) {
+func getProcessStats(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	//load data
 	data := getProcessStatsData{}
 	err := json.Unmarshal(*cmd.Arguments, &data)
@@ -0,0 +1,75 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"sort"
+)
+
+const (
+	cmdHealthStatus = "health.status"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdHealthStatus, healthStatus)
+}
+
+type healthStatusData struct {
+	ID string `json:"id"`
+}
+
+type jobHealth struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	pm.HealthStatus
+}
+
+type jobsHealth []jobHealth
+
+func (h jobsHealth) Len() int           { return len(h) }
+func (h jobsHealth) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h jobsHealth) Less(i, j int) bool { return h[i].ID < h[j].ID }
+
+func healthStatus(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	var data healthStatusData
+	if cmd.Arguments != nil {
+		if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	jobs := make(jobsHealth, 0)
+	for _, runner := range pm.GetManager().RunnerList() {
+		if data.ID != "" && runner.Command().ID != data.ID {
+			continue
+		}
+
+		c := runner.Command()
+		if c.HealthCheck == nil {
+			continue
+		}
+
+		job := jobHealth{
+			ID:      c.ID,
+			Command: c.Command,
+			//the probe starts with the first run, until then the job is starting.
+			HealthStatus: pm.HealthStatus{Status: pm.HealthStarting},
+		}
+
+		if status := runner.Health(); status != nil {
+			job.HealthStatus = *status
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if data.ID != "" && len(jobs) == 0 {
+		return nil, fmt.Errorf("no job with id '%s' and a health check", data.ID)
+	}
+
+	sort.Sort(jobs)
+	return jobs, nil
+}
@@ -1,10 +1,10 @@
 package builtin
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 )
 
 const (
@@ -12,14 +12,14 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdKill] = process.NewInternalProcessFactory(kill)
+	pm.RegisterBuiltIn(cmdKill, kill)
 }
 
 type killData struct {
 	ID string `json:"id"`
 }
 
-func kill(cmd *core.Command) (interface{}, error) {
+func kill(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	//load data
 	data := killData{}
 	err := json.Unmarshal(*cmd.Arguments, &data)
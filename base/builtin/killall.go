@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 )
 
 const (
@@ -11,10 +11,10 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdKillAll] = process.NewInternalProcessFactory(killall)
+	pm.RegisterBuiltIn(cmdKillAll, killall)
 }
 
-func killall(cmd *core.Command) (interface{}, error) {
+func killall(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	pm.GetManager().Killall()
 	return true, nil
 }
@@ -0,0 +1,77 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+)
+
+const (
+	cmdJobOutput = "job.output"
+
+	jobOutputMaxLength = 1024 * 1024
+	jobOutputMaxTail   = 10000
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdJobOutput, jobOutput)
+}
+
+type jobOutputData struct {
+	ID     string `json:"id"`
+	Stream string `json:"stream"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Tail   int    `json:"tail"`
+}
+
+type jobOutputResult struct {
+	Data string `json:"data"`
+	//Offset of the returned data, and Size the total size of the captured stream
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+func jobOutput(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	var data jobOutputData
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return nil, err
+	}
+
+	if data.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	if data.Stream == "" {
+		data.Stream = pm.OutputStdout
+	}
+
+	if data.Tail > jobOutputMaxTail {
+		data.Tail = jobOutputMaxTail
+	}
+
+	if data.Tail > 0 {
+		return pm.GetManager().TailOutput(data.ID, data.Stream, data.Tail)
+	}
+
+	if data.Offset < 0 {
+		return nil, fmt.Errorf("invalid offset '%d'", data.Offset)
+	}
+
+	if data.Length <= 0 || data.Length > jobOutputMaxLength {
+		data.Length = jobOutputMaxLength
+	}
+
+	output, size, err := pm.GetManager().ReadOutput(data.ID, data.Stream, data.Offset, data.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobOutputResult{
+		Data:   string(output),
+		Offset: data.Offset,
+		Size:   size,
+	}, nil
+}
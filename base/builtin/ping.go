@@ -1,10 +1,10 @@
 package builtin
 
 import (
+	"context"
 	"fmt"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"time"
 )
 
@@ -13,9 +13,9 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdPing] = process.NewInternalProcessFactory(ping)
+	pm.RegisterBuiltIn(cmdPing, ping)
 }
 
-func ping(cmd *core.Command) (interface{}, error) {
+func ping(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	return fmt.Sprintf("PONG %s", time.Now()), nil
 }
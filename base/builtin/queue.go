@@ -0,0 +1,84 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+)
+
+const (
+	cmdQueueList   = "queue.list"
+	cmdQueueCancel = "queue.cancel"
+	cmdQueuePause  = "queue.pause"
+	cmdQueueResume = "queue.resume"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdQueueList, queueList)
+	pm.RegisterBuiltIn(cmdQueueCancel, queueCancel)
+	pm.RegisterBuiltIn(cmdQueuePause, queuePause)
+	pm.RegisterBuiltIn(cmdQueueResume, queueResume)
+}
+
+type queueCancelData struct {
+	ID string `json:"id"`
+}
+
+type queueData struct {
+	Name string `json:"name"`
+}
+
+func getQueueName(cmd *core.Command) (string, error) {
+	data := queueData{}
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return "", err
+	}
+
+	if data.Name == "" {
+		return "", fmt.Errorf("queue name is required")
+	}
+
+	return data.Name, nil
+}
+
+func queueList(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	return pm.GetManager().Queues(), nil
+}
+
+func queueCancel(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	data := queueCancelData{}
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return nil, err
+	}
+
+	if err := pm.GetManager().CancelQueued(data.ID); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func queuePause(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getQueueName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.GetManager().PauseQueue(name)
+	return true, nil
+}
+
+func queueResume(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getQueueName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pm.GetManager().ResumeQueue(name); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/process"
+)
+
+const (
+	cmdResize = "process.resize"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdResize, resize)
+}
+
+type resizeData struct {
+	ID   string `json:"id"`
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+}
+
+func resize(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	//load data
+	data := resizeData{}
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return nil, err
+	}
+
+	runner, err := getRunner(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ps, ok := runner.Process().(process.TTYProcess)
+	if !ok {
+		return nil, fmt.Errorf("process with id '%s' is not running or has no tty", data.ID)
+	}
+
+	if err := ps.Resize(data.Rows, data.Cols); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
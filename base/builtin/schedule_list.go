@@ -0,0 +1,71 @@
+package builtin
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"sort"
+)
+
+const (
+	cmdScheduleList = "schedule.list"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdScheduleList, scheduleList)
+}
+
+type scheduledJob struct {
+	ID              string `json:"id"`
+	Command         string `json:"command"`
+	Schedule        string `json:"schedule,omitempty"`
+	RecurringPeriod int    `json:"recurring_period,omitempty"`
+	Running         bool   `json:"running"`
+	//CrashLoop is set if the job is restarted over and over with no stable run
+	CrashLoop bool `json:"crash_loop"`
+	//Next fire time (unix seconds), 0 if the job is currently running
+	Next int64 `json:"next"`
+}
+
+type scheduledJobs []scheduledJob
+
+func (s scheduledJobs) Len() int      { return len(s) }
+func (s scheduledJobs) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s scheduledJobs) Less(i, j int) bool {
+	//running jobs goes last.
+	if s[i].Next == 0 || s[j].Next == 0 {
+		return s[j].Next == 0 && s[i].Next != 0
+	}
+
+	return s[i].Next < s[j].Next
+}
+
+func scheduleList(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	jobs := make(scheduledJobs, 0)
+
+	for _, runner := range pm.GetManager().RunnerList() {
+		c := runner.Command()
+		crashLoop := runner.CrashLoop()
+		if c.Schedule == "" && c.RecurringPeriod <= 0 && !crashLoop {
+			continue
+		}
+
+		job := scheduledJob{
+			ID:              c.ID,
+			Command:         c.Command,
+			Schedule:        c.Schedule,
+			RecurringPeriod: c.RecurringPeriod,
+			Running:         runner.Process() != nil,
+			CrashLoop:       crashLoop,
+		}
+
+		if next := runner.NextRun(); !next.IsZero() {
+			job.Next = next.Unix()
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Sort(jobs)
+	return jobs, nil
+}
@@ -0,0 +1,43 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/process"
+)
+
+const (
+	cmdSignal = "process.signal"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdSignal, signal)
+}
+
+type signalData struct {
+	ID     string `json:"id"`
+	Signal string `json:"signal"`
+}
+
+func signal(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	//load data
+	data := signalData{}
+	err := json.Unmarshal(*cmd.Arguments, &data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := process.ParseSignal(data.Signal)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pm.GetManager().Signal(data.ID, sig); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
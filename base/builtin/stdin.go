@@ -0,0 +1,81 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+)
+
+const (
+	cmdWrite      = "process.write"
+	cmdCloseStdin = "process.close_stdin"
+)
+
+func init() {
+	pm.RegisterBuiltIn(cmdWrite, write)
+	pm.RegisterBuiltIn(cmdCloseStdin, closeStdin)
+}
+
+type writeData struct {
+	ID   string `json:"id"`
+	Data string `json:"data"`
+}
+
+type closeStdinData struct {
+	ID string `json:"id"`
+}
+
+func getRunner(id string) (pm.Runner, error) {
+	runner, ok := pm.GetManager().Runner(id)
+	if !ok {
+		return nil, fmt.Errorf("Process with id '%s' doesn't exist", id)
+	}
+
+	return runner, nil
+}
+
+func write(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	//load data
+	data := writeData{}
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return nil, err
+	}
+
+	runner, err := getRunner(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := runner.Stdin()
+	if err != nil {
+		return nil, err
+	}
+
+	return stdin.Write([]byte(data.Data))
+}
+
+func closeStdin(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	//load data
+	data := closeStdinData{}
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return nil, err
+	}
+
+	runner, err := getRunner(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := runner.Stdin()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stdin.Close(); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
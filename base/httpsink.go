@@ -0,0 +1,466 @@
+package core
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/g8os/core0/base/settings"
+	"github.com/pborman/uuid"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//StateRunning is reported for jobs that have no result yet
+	StateRunning = "RUNNING"
+
+	httpStreamBuffer = 1000
+
+	//httpJobExpire is how long a submitted job that is not running is tracked without being answered (ex: waiting
+	//on a queue, or lost by a restart), after that it's forgotten
+	httpJobExpire = 1 * time.Hour
+	//httpJobSweep is the interval between 2 checks of the unanswered jobs
+	httpJobSweep = 1 * time.Minute
+)
+
+//httpJob tracks a job submitted to (or answered through) the http sink
+type httpJob struct {
+	result *core.JobResult
+	done   chan struct{}
+	//seen is the last time the job was submitted or found running, while it has no result
+	seen time.Time
+}
+
+//httpSubscriber receives the messages and the result of a job streamed over a websocket
+type httpSubscriber struct {
+	messages chan *stream.Message
+	result   chan *core.JobResult
+}
+
+//httpStreamEvent is a single websocket frame of a job stream, either a log message or the final result
+type httpStreamEvent struct {
+	Message *stream.Message `json:"message,omitempty"`
+	Result  *core.JobResult `json:"result,omitempty"`
+}
+
+//JobInfo describes a running job
+type JobInfo struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Tags    string `json:"tags"`
+	//StartTime of the running process (unix milliseconds), 0 if the job is waiting for a restart
+	StartTime int64 `json:"start_time"`
+}
+
+type jobInfos []JobInfo
+
+func (j jobInfos) Len() int           { return len(j) }
+func (j jobInfos) Swap(i, k int)      { j[i], j[k] = j[k], j[i] }
+func (j jobInfos) Less(i, k int) bool { return j[i].ID < j[k].ID }
+
+/*
+httpSinkClient is a sink that receives commands over an http API instead of polling a redis queue. Submitted
+commands are handed to the sink through GetNext, and results come back through Respond, so they go through the same
+routing as the redis sinks.
+*/
+type httpSinkClient struct {
+	url      string
+	base     string
+	password string
+	commands chan *core.Command
+
+	jobs        map[string]*httpJob
+	subscribers map[string]map[*httpSubscriber]bool
+	m           sync.Mutex
+}
+
+/*
+NewHTTPSinkClient starts an http listener on the host of the url (http or https), the path of the url is the base
+path of the api. The password must be sent as a bearer token.
+*/
+func NewHTTPSinkClient(cfg *settings.SinkConfig) (SinkClient, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Password == "" {
+		return nil, fmt.Errorf("http sink requires a password")
+	}
+
+	if u.Scheme == "https" && (cfg.Certificate == "" || cfg.CertificateKey == "") {
+		return nil, fmt.Errorf("https sink requires a certificate and a certificate key")
+	}
+
+	client := &httpSinkClient{
+		url:         strings.TrimRight(cfg.URL, "/"),
+		base:        strings.TrimRight(u.Path, "/"),
+		password:    cfg.Password,
+		commands:    make(chan *core.Command),
+		jobs:        make(map[string]*httpJob),
+		subscribers: make(map[string]map[*httpSubscriber]bool),
+	}
+
+	pm.GetManager().AddMessageHandler(client.message)
+	pm.GetManager().AddResultHandler(client.result)
+
+	go func() {
+		for now := range time.Tick(httpJobSweep) {
+			client.expire(now, func(id string) bool {
+				_, ok := pm.GetManager().Runner(id)
+				return ok
+			})
+		}
+	}()
+
+	server := &http.Server{
+		Addr:    u.Host,
+		Handler: client,
+	}
+
+	go func() {
+		var err error
+		if u.Scheme == "https" {
+			err = server.ListenAndServeTLS(cfg.Certificate, cfg.CertificateKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		log.Errorf("Http sink %s stopped: %s", client.url, err)
+	}()
+
+	return client, nil
+}
+
+func (client *httpSinkClient) String() string {
+	return client.url
+}
+
+func (client *httpSinkClient) GetNext(command *core.Command) error {
+	*command = *<-client.commands
+	return nil
+}
+
+func (client *httpSinkClient) Respond(result *core.JobResult) error {
+	if result.ID == "" {
+		return fmt.Errorf("result with no ID, not keeping results...")
+	}
+
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	job, ok := client.jobs[result.ID]
+	if !ok || job.result != nil {
+		job = &httpJob{done: make(chan struct{})}
+		client.jobs[result.ID] = job
+	}
+
+	job.result = result
+	close(job.done)
+
+	//same expiry as the results pushed to redis
+	time.AfterFunc(ReturnExpire*time.Second, func() {
+		client.m.Lock()
+		defer client.m.Unlock()
+		if client.jobs[result.ID] == job {
+			delete(client.jobs, result.ID)
+		}
+	})
+
+	return nil
+}
+
+//message feeds the subscribers of the job with its log messages
+func (client *httpSinkClient) message(cmd *core.Command, msg *stream.Message) {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	for subscriber := range client.subscribers[cmd.ID] {
+		select {
+		case subscriber.messages <- msg:
+		default:
+			//never hold back the job for a slow client
+		}
+	}
+}
+
+//result ends the streams of the job, for jobs of any sink
+func (client *httpSinkClient) result(cmd *core.Command, result *core.JobResult) {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	for subscriber := range client.subscribers[cmd.ID] {
+		subscriber.result <- result
+	}
+
+	delete(client.subscribers, cmd.ID)
+}
+
+func (client *httpSinkClient) subscribe(id string) *httpSubscriber {
+	subscriber := &httpSubscriber{
+		messages: make(chan *stream.Message, httpStreamBuffer),
+		result:   make(chan *core.JobResult, 1),
+	}
+
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	if _, ok := client.subscribers[id]; !ok {
+		client.subscribers[id] = make(map[*httpSubscriber]bool)
+	}
+
+	client.subscribers[id][subscriber] = true
+	return subscriber
+}
+
+func (client *httpSinkClient) unsubscribe(id string, subscriber *httpSubscriber) {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	delete(client.subscribers[id], subscriber)
+	if len(client.subscribers[id]) == 0 {
+		delete(client.subscribers, id)
+	}
+}
+
+/*
+expire forgets the unanswered jobs that were not seen running for httpJobExpire, their results would otherwise never
+be released. A late result is still kept by Respond.
+*/
+func (client *httpSinkClient) expire(now time.Time, running func(id string) bool) {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	for id, job := range client.jobs {
+		if job.result != nil {
+			continue
+		}
+
+		if running(id) {
+			job.seen = now
+		} else if now.Sub(job.seen) > httpJobExpire {
+			log.Warningf("Forgetting job '%s' of http sink %s, it was never answered", id, client.url)
+			delete(client.jobs, id)
+		}
+	}
+}
+
+func (client *httpSinkClient) job(id string) (*httpJob, bool) {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	job, ok := client.jobs[id]
+	return job, ok
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (client *httpSinkClient) authorized(r *http.Request) bool {
+	if client.password == "" {
+		return false
+	}
+
+	//constant time, so the token can't be guessed from the response times
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+client.password)) == 1
+}
+
+func (client *httpSinkClient) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !client.authorized(r) {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, client.base)
+	switch {
+	case path == "/jobs" && r.Method == "POST":
+		client.submit(w, r)
+	case path == "/jobs" && r.Method == "GET":
+		client.list(w, r)
+	case strings.HasPrefix(path, "/jobs/") && strings.HasSuffix(path, "/stream") && r.Method == "GET":
+		client.stream(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/jobs/"), "/stream"))
+	case strings.HasPrefix(path, "/jobs/") && r.Method == "GET":
+		client.get(w, r, strings.TrimPrefix(path, "/jobs/"))
+	case strings.HasPrefix(path, "/jobs/") && r.Method == "DELETE":
+		client.kill(w, r, strings.TrimPrefix(path, "/jobs/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+//submit queues a command, the id of the job is returned
+func (client *httpSinkClient) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cmd, err := core.LoadCmd(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if cmd.ID == "" {
+		cmd.ID = uuid.New()
+	}
+
+	client.m.Lock()
+	if job, ok := client.jobs[cmd.ID]; ok && job.result == nil {
+		client.m.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("job '%s' is already running", cmd.ID))
+		return
+	}
+	client.jobs[cmd.ID] = &httpJob{done: make(chan struct{}), seen: time.Now()}
+	client.m.Unlock()
+
+	client.commands <- cmd
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": cmd.ID})
+}
+
+//list gets the running jobs
+func (client *httpSinkClient) list(w http.ResponseWriter, r *http.Request) {
+	jobs := make(jobInfos, 0)
+	for _, runner := range pm.GetManager().RunnerList() {
+		cmd := runner.Command()
+		info := JobInfo{
+			ID:      cmd.ID,
+			Command: cmd.Command,
+			Tags:    cmd.Tags,
+		}
+
+		if started := runner.StartTime(); !started.IsZero() {
+			info.StartTime = int64(time.Duration(started.UnixNano()) / time.Millisecond)
+		}
+
+		jobs = append(jobs, info)
+	}
+
+	sort.Sort(jobs)
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+//get gets the result of a job, with `wait` (seconds) it waits for the job to exit
+func (client *httpSinkClient) get(w http.ResponseWriter, r *http.Request, id string) {
+	job, ok := client.job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown job '%s'", id))
+		return
+	}
+
+	if wait := r.URL.Query().Get("wait"); wait != "" {
+		seconds, err := strconv.Atoi(wait)
+		if err != nil || seconds < 0 || seconds > ReturnExpire {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("wait must be between 0 and %d seconds", ReturnExpire))
+			return
+		}
+
+		timer := time.NewTimer(time.Duration(seconds) * time.Second)
+		defer timer.Stop()
+
+		select {
+		case <-job.done:
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	select {
+	case <-job.done:
+		writeJSON(w, http.StatusOK, job.result)
+	default:
+		writeJSON(w, http.StatusAccepted, map[string]string{"id": id, "state": StateRunning})
+	}
+}
+
+//kill kills a running job
+func (client *httpSinkClient) kill(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := pm.GetManager().Runner(id); !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job '%s' is not running", id))
+		return
+	}
+
+	pm.GetManager().Kill(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//stream streams the log messages of a running job over a websocket, the last frame is the job result
+func (client *httpSinkClient) stream(w http.ResponseWriter, r *http.Request, id string) {
+	subscriber := client.subscribe(id)
+	defer client.unsubscribe(id, subscriber)
+
+	var result *core.JobResult
+	if _, running := pm.GetManager().Runner(id); !running {
+		job, ok := client.job(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown job '%s'", id))
+			return
+		}
+
+		select {
+		case <-job.done:
+			result = job.result
+		default:
+			//not dispatched yet
+		}
+	}
+
+	ws, err := upgradeWebsocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer ws.Close()
+
+	send := func(event httpStreamEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		return ws.WriteText(data)
+	}
+
+	if result != nil {
+		send(httpStreamEvent{Result: result})
+		return
+	}
+
+	closed := ws.Serve()
+	for {
+		select {
+		case msg := <-subscriber.messages:
+			if err := send(httpStreamEvent{Message: msg}); err != nil {
+				return
+			}
+		case result := <-subscriber.result:
+			//flush the messages that came before the result
+			for len(subscriber.messages) > 0 {
+				send(httpStreamEvent{Message: <-subscriber.messages})
+			}
+
+			send(httpStreamEvent{Result: result})
+			return
+		case <-closed:
+			return
+		}
+	}
+}
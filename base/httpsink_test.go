@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebsocketAccept(t *testing.T) {
+	//sample handshake of RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestHTTPSinkSubmit(t *testing.T) {
+	client := &httpSinkClient{
+		base:        "/api",
+		password:    "secret",
+		commands:    make(chan *core.Command),
+		jobs:        make(map[string]*httpJob),
+		subscribers: make(map[string]map[*httpSubscriber]bool),
+	}
+
+	server := httptest.NewServer(client)
+	defer server.Close()
+
+	do := func(method, path string, body []byte) (*http.Response, map[string]interface{}) {
+		request, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		request.Header.Set("Authorization", "Bearer secret")
+		response, err := http.DefaultClient.Do(request)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer response.Body.Close()
+
+		var data map[string]interface{}
+		json.NewDecoder(response.Body).Decode(&data)
+		return response, data
+	}
+
+	go func() {
+		var cmd core.Command
+		client.GetNext(&cmd)
+		client.Respond(&core.JobResult{ID: cmd.ID, Command: cmd.Command, State: core.StateSuccess})
+	}()
+
+	response, data := do("POST", "/api/jobs", []byte(`{"id": "job", "command": "core.ping", "arguments": {}}`))
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, "job", data["id"])
+
+	response, data = do("GET", "/api/jobs/job?wait=5", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, core.StateSuccess, data["state"])
+
+	response, _ = do("GET", "/api/jobs/unknown", nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	for _, token := range []string{"", "Bearer other", "Bearer secretsecret"} {
+		request, _ := http.NewRequest("GET", server.URL+"/api/jobs/job", nil)
+		request.Header.Set("Authorization", token)
+		response, err := http.DefaultClient.Do(request)
+		if assert.Nil(t, err) {
+			response.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, response.StatusCode, token)
+		}
+	}
+}
+
+func TestHTTPSinkExpire(t *testing.T) {
+	now := time.Now()
+	client := &httpSinkClient{
+		jobs: map[string]*httpJob{
+			"lost":     {done: make(chan struct{}), seen: now},
+			"running":  {done: make(chan struct{}), seen: now},
+			"answered": {done: make(chan struct{}), seen: now, result: &core.JobResult{ID: "answered"}},
+		},
+	}
+
+	running := func(id string) bool {
+		return id == "running"
+	}
+
+	client.expire(now.Add(httpJobExpire/2), running)
+	assert.Len(t, client.jobs, 3)
+
+	//the running job was seen at the last sweep, answered jobs have their own expiry
+	client.expire(now.Add(httpJobExpire+time.Minute), running)
+	_, lost := client.jobs["lost"]
+	assert.False(t, lost)
+	assert.Len(t, client.jobs, 2)
+}
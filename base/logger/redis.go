@@ -6,7 +6,6 @@ import (
 	"github.com/g8os/core0/base/pm/stream"
 	"github.com/g8os/core0/base/utils"
 	"github.com/garyburd/redigo/redis"
-	"strings"
 )
 
 const (
@@ -24,19 +23,16 @@ type redisLogger struct {
 	ch chan *LogRecord
 }
 
-// NewRedisLogger creates new redis logger handler
-func NewRedisLogger(coreID uint16, address string, password string, defaults []int, batchSize int) Logger {
+// NewRedisLogger creates new redis logger handler, address is a host:port, a unix socket path or a redis url
+// (rediss:// urls use the given certificate files)
+func NewRedisLogger(coreID uint16, address string, password string, files utils.TLSFiles, defaults []int, batchSize int) Logger {
 	if batchSize == 0 {
 		batchSize = MaxRedisQueueSize
 	}
-	network := "unix"
-	if strings.Index(address, ":") > 0 {
-		network = "tcp"
-	}
 
 	rl := &redisLogger{
 		coreID:    coreID,
-		pool:      utils.NewRedisPool(network, address, password),
+		pool:      utils.NewRedisPoolAddress(address, password, files),
 		defaults:  defaults,
 		queueSize: batchSize,
 		ch:        make(chan *LogRecord, MaxRedisQueueSize),
@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/g8os/core0/base/pm/core"
+	"time"
+)
+
+const (
+	//DefaultResultRetention how long job results are kept if no retention is configured
+	DefaultResultRetention = 7 * 24 * time.Hour
+
+	resultsBucket      = "results"
+	resultsIdxBucket   = "results.index"
+	resultsPrunePeriod = time.Hour
+)
+
+//ResultQuery filters the stored job results, empty fields match everything
+type ResultQuery struct {
+	Command string `json:"command"`
+	State   string `json:"state"`
+	Tags    string `json:"tags"`
+	//From and To limit the start time of the results (unix seconds)
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	Limit int   `json:"limit"`
+}
+
+func (q *ResultQuery) match(result *core.JobResult) bool {
+	if q.Command != "" && q.Command != result.Command {
+		return false
+	}
+
+	if q.State != "" && q.State != result.State {
+		return false
+	}
+
+	if q.Tags != "" && q.Tags != result.Tags {
+		return false
+	}
+
+	return true
+}
+
+/*
+ResultStore keeps the job results in a bolt database. Results are stored in the `results` bucket ordered by start
+time, and the `results.index` bucket maps a job id to its latest result. Results older than the retention are
+pruned periodically.
+*/
+type ResultStore struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+//NewResultStore creates a new result store in the given database, retention 0 means DefaultResultRetention
+func NewResultStore(db *bolt.DB, retention time.Duration) (*ResultStore, error) {
+	if retention <= 0 {
+		retention = DefaultResultRetention
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{resultsBucket, resultsIdxBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	store := &ResultStore{
+		db:        db,
+		retention: retention,
+	}
+
+	go store.pruner()
+	return store, nil
+}
+
+func resultKey(result *core.JobResult) []byte {
+	start := result.StartTime
+	if start == 0 {
+		//results of commands that never started (unknown command, duplicate id, etc...)
+		start = int64(time.Duration(time.Now().UnixNano()) / time.Millisecond)
+	}
+
+	return []byte(fmt.Sprintf("%020d-%s", start, result.ID))
+}
+
+//Store saves the job result, it can be used as a result handler of the process manager
+func (store *ResultStore) Store(cmd *core.Command, result *core.JobResult) {
+	go store.db.Batch(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(result)
+		if err != nil {
+			log.Errorf("%s", err)
+			return err
+		}
+
+		key := resultKey(result)
+		if err := tx.Bucket([]byte(resultsBucket)).Put(key, value); err != nil {
+			log.Errorf("Failed to store result of %s: %s", cmd, err)
+			return err
+		}
+
+		//results are stored asynchronously, so the index is only moved forward.
+		index := tx.Bucket([]byte(resultsIdxBucket))
+		if latest := index.Get([]byte(result.ID)); latest != nil && string(latest) > string(key) {
+			return nil
+		}
+
+		return index.Put([]byte(result.ID), key)
+	})
+}
+
+//Get gets the latest result of the job with the given id
+func (store *ResultStore) Get(id string) (*core.JobResult, error) {
+	var result *core.JobResult
+	err := store.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte(resultsIdxBucket)).Get([]byte(id))
+		if key == nil {
+			return fmt.Errorf("no result for job '%s'", id)
+		}
+
+		value := tx.Bucket([]byte(resultsBucket)).Get(key)
+		if value == nil {
+			return fmt.Errorf("no result for job '%s'", id)
+		}
+
+		result = &core.JobResult{}
+		return json.Unmarshal(value, result)
+	})
+
+	return result, err
+}
+
+//Query gets the results that match the query, latest first
+func (store *ResultStore) Query(query *ResultQuery) ([]*core.JobResult, error) {
+	results := make([]*core.JobResult, 0)
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(resultsBucket)).Cursor()
+
+		var key, value []byte
+		if query.To > 0 {
+			//seek to the first key after the end of the range, and go back from there.
+			key, value = cursor.Seek([]byte(fmt.Sprintf("%020d", (query.To+1)*1000)))
+			if key == nil {
+				key, value = cursor.Last()
+			} else {
+				key, value = cursor.Prev()
+			}
+		} else {
+			key, value = cursor.Last()
+		}
+
+		from := []byte(fmt.Sprintf("%020d", query.From*1000))
+		for ; key != nil && string(key) >= string(from); key, value = cursor.Prev() {
+			if query.Limit > 0 && len(results) >= query.Limit {
+				break
+			}
+
+			result := &core.JobResult{}
+			if err := json.Unmarshal(value, result); err != nil {
+				log.Errorf("Failed to load job result '%s': %s", key, err)
+				continue
+			}
+
+			if query.match(result) {
+				results = append(results, result)
+			}
+		}
+
+		return nil
+	})
+
+	return results, err
+}
+
+//prune deletes the results older than the retention
+func (store *ResultStore) prune() error {
+	limit := []byte(fmt.Sprintf("%020d", int64(time.Duration(time.Now().Add(-store.retention).UnixNano())/time.Millisecond)))
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		results := tx.Bucket([]byte(resultsBucket))
+		index := tx.Bucket([]byte(resultsIdxBucket))
+
+		var keys [][]byte
+		cursor := results.Cursor()
+		for key, _ := cursor.First(); key != nil && string(key) < string(limit); key, _ = cursor.Next() {
+			keys = append(keys, append([]byte(nil), key...))
+		}
+
+		for _, key := range keys {
+			//drop the index entry only if it still points to this result
+			id := key[21:]
+			if latest := index.Get(id); latest != nil && string(latest) == string(key) {
+				if err := index.Delete(id); err != nil {
+					return err
+				}
+			}
+
+			if err := results.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (store *ResultStore) pruner() {
+	for {
+		if err := store.prune(); err != nil {
+			log.Errorf("Failed to prune job results: %s", err)
+		}
+
+		<-time.After(resultsPrunePeriod)
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, retention time.Duration) (*ResultStore, func()) {
+	dir, err := ioutil.TempDir("", "results")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(path.Join(dir, "logs.db"), 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewResultStore(db, retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func storeSync(t *testing.T, store *ResultStore, results ...*core.JobResult) {
+	for _, result := range results {
+		store.Store(&core.Command{ID: result.ID}, result)
+	}
+
+	//Store is asynchronous, wait until all the results are written
+	for i := 0; i < 100; i++ {
+		all, _ := store.Query(&ResultQuery{})
+		if len(all) == len(results) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("results were not stored")
+}
+
+func TestResultStoreQuery(t *testing.T) {
+	store, cleanup := newTestStore(t, 0)
+	defer cleanup()
+
+	now := time.Now().Unix() * 1000
+	storeSync(t, store,
+		&core.JobResult{ID: "a", Command: "core.system", State: core.StateSuccess, StartTime: now - 3000},
+		&core.JobResult{ID: "b", Command: "core.ping", State: core.StateError, StartTime: now - 2000},
+		&core.JobResult{ID: "a", Command: "core.system", State: core.StateError, StartTime: now - 1000},
+	)
+
+	result, err := store.Get("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, core.StateError, result.State)
+	}
+
+	_, err = store.Get("c")
+	assert.Error(t, err)
+
+	results, err := store.Query(&ResultQuery{Command: "core.system"})
+	if assert.NoError(t, err) && assert.Len(t, results, 2) {
+		//latest first
+		assert.Equal(t, now-1000, results[0].StartTime)
+	}
+
+	results, _ = store.Query(&ResultQuery{State: core.StateError, Limit: 1})
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "a", results[0].ID)
+	}
+
+	results, _ = store.Query(&ResultQuery{From: now/1000 - 2, To: now/1000 - 2})
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "b", results[0].ID)
+	}
+}
+
+func TestResultStorePrune(t *testing.T) {
+	store, cleanup := newTestStore(t, time.Hour)
+	defer cleanup()
+
+	now := time.Now().Unix() * 1000
+	storeSync(t, store,
+		&core.JobResult{ID: "old", StartTime: now - 2*3600*1000},
+		&core.JobResult{ID: "new", StartTime: now},
+	)
+
+	assert.NoError(t, store.prune())
+
+	_, err := store.Get("old")
+	assert.Error(t, err)
+
+	results, _ := store.Query(&ResultQuery{})
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "new", results[0].ID)
+	}
+}
@@ -5,32 +5,148 @@ package pm
 import (
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/process"
+	"os"
+	"strings"
+	"sync"
 )
 
+//command is a registered command, internal commands are handled by a go function and not by an external process
+type command struct {
+	factory  process.ProcessFactory
+	internal bool
+}
+
 /*
 Global command ProcessConstructor registery
 */
-var CmdMap = map[string]process.ProcessFactory{
-	process.CommandSystem: process.NewSystemProcess,
+var (
+	cmdMap = map[string]command{
+		process.CommandSystem: {factory: process.NewSystemProcess},
+	}
+
+	//cmdMapMux protects cmdMap, extensions are registered and unregistered by reloads while commands are dispatched
+	cmdMapMux sync.RWMutex
+)
+
+//getCmd gets a registered command
+func getCmd(cmd string) command {
+	cmdMapMux.RLock()
+	defer cmdMapMux.RUnlock()
+
+	return cmdMap[cmd]
+}
+
+//setCmd registers a command, a nil factory removes it
+func setCmd(cmd string, c command) {
+	cmdMapMux.Lock()
+	defer cmdMapMux.Unlock()
+
+	if c.factory == nil {
+		delete(cmdMap, cmd)
+		return
+	}
+
+	cmdMap[cmd] = c
 }
 
 /*
 NewProcess creates a new process from a command
 */
 func GetProcessFactory(cmd *core.Command) process.ProcessFactory {
-	return CmdMap[cmd.Command]
+	return getCmd(cmd.Command).factory
+}
+
+/*
+IsBuiltIn checks if the command is handled by an internal (go) process
+*/
+func IsBuiltIn(cmd *core.Command) bool {
+	return getCmd(cmd.Command).internal
+}
+
+/*
+RegisterBuiltIn registers an internal command, the runnable is called in the core process for each call of the
+command
+*/
+func RegisterBuiltIn(cmd string, runnable process.Runnable) {
+	setCmd(cmd, command{
+		factory:  process.NewInternalProcessFactory(runnable),
+		internal: true,
+	})
 }
 
 /*
 RegisterCmd registers a new command (extension) so it can be executed via commands
 */
-func RegisterCmd(cmd string, exe string, workdir string, cmdargs []string, env map[string]string) {
-	CmdMap[cmd] = process.NewExtensionProcessFactory(exe, workdir, cmdargs, env)
+func RegisterCmd(cmd string, exe string, workdir string, cmdargs []string, env map[string]string, creds process.Credentials) {
+	//the extension may have been a daemon before a reload
+	stopDaemon(cmd)
+	setCmd(cmd, command{factory: process.NewExtensionProcessFactory(exe, workdir, cmdargs, env, creds)})
+}
+
+//DaemonJobPrefix prefixes the ids of the jobs that run the extensions daemons, the sinks reject commands using it
+const DaemonJobPrefix = "extension."
+
+//DaemonJobID gets the id of the job that runs the daemon of the given extension
+func DaemonJobID(cmd string) string {
+	return DaemonJobPrefix + cmd
+}
+
+//IsReservedID checks if the job id is reserved for the jobs started by the core itself
+func IsReservedID(id string) bool {
+	return strings.HasPrefix(id, DaemonJobPrefix)
+}
+
+/*
+RegisterDaemonCmd registers an extension that runs as a long lived daemon. The daemon is started once as a job that
+is restarted if it crashes, it gets the path of the unix socket it must listen on in the CORE_SOCKET env variable.
+Each call of the command is then sent to the daemon over the socket. Registering an extension again restarts its
+daemon.
+*/
+func RegisterDaemonCmd(cmd string, exe string, workdir string, cmdargs []string, env map[string]string, creds process.Credentials, socket string) {
+	stopDaemon(cmd)
+	os.Remove(socket)
+
+	denv := map[string]string{}
+	for k, v := range env {
+		denv[k] = v
+	}
+	denv["CORE_SOCKET"] = socket
+
+	daemon := &core.Command{
+		ID:      DaemonJobID(cmd),
+		Command: process.CommandSystem,
+		Arguments: core.MustArguments(process.SystemCommandArguments{
+			Name:        exe,
+			Dir:         workdir,
+			Args:        cmdargs,
+			Env:         denv,
+			Credentials: creds,
+		}),
+		RestartPolicy: core.RestartPolicyAlways,
+	}
+
+	setCmd(cmd, command{factory: process.NewDaemonProcessFactory(socket)})
+	if _, err := GetManager().RunCmd(daemon); err != nil {
+		log.Errorf("Failed to start daemon of extension '%s': %s", cmd, err)
+	}
+}
+
+//stopDaemon kills the daemon of the extension if it's running, once it returns the daemon job id can be used again
+func stopDaemon(cmd string) {
+	if pm == nil {
+		return
+	}
+
+	if runner, ok := pm.Runner(DaemonJobID(cmd)); ok {
+		runner.Kill()
+		runner.Wait()
+	}
 }
 
 /*
-UnregisterCmd removes an extension from the global registery
+UnregisterCmd removes an extension from the global registery, the daemon of the extension is stopped if it has one
 */
 func UnregisterCmd(cmd string) {
-	delete(CmdMap, cmd)
+	setCmd(cmd, command{})
+	stopDaemon(cmd)
 }
@@ -0,0 +1,51 @@
+package pm
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/process"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsBuiltIn(t *testing.T) {
+	RegisterBuiltIn("test.builtin", func(ctx context.Context, cmd *core.Command) (interface{}, error) {
+		return nil, nil
+	})
+	defer UnregisterCmd("test.builtin")
+
+	RegisterCmd("test.extension", "/bin/echo", "", []string{"{arg}"}, nil, process.Credentials{})
+	defer UnregisterCmd("test.extension")
+
+	assert.True(t, IsBuiltIn(&core.Command{Command: "test.builtin"}))
+	//extensions arguments are not even looked at
+	assert.False(t, IsBuiltIn(&core.Command{Command: "test.extension"}))
+	assert.False(t, IsBuiltIn(&core.Command{Command: process.CommandSystem}))
+	assert.False(t, IsBuiltIn(&core.Command{Command: "test.unknown"}))
+}
+
+//TestReloadWhileDispatching registers and unregisters an extension like a reload does while commands are being
+//dispatched, run with -race to catch unprotected accesses to the commands registry
+func TestReloadWhileDispatching(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			RegisterCmd("test.reload", "/bin/echo", "", nil, nil, process.Credentials{})
+			UnregisterCmd("test.reload")
+		}
+	}()
+
+	cmd := &core.Command{Command: "test.reload"}
+	for {
+		select {
+		case <-done:
+			assert.Nil(t, GetProcessFactory(cmd))
+			return
+		default:
+		}
+
+		assert.False(t, IsBuiltIn(cmd))
+		GetProcessFactory(cmd)
+	}
+}
@@ -0,0 +1,378 @@
+package pm
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/g8os/core0/base/pm/stream"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//DefaultCaptureMaxSize max size of a capture file before it's rotated
+	DefaultCaptureMaxSize = 10 * 1024 * 1024
+	//DefaultCaptureMaxFiles number of rotated files kept per job stream
+	DefaultCaptureMaxFiles = 5
+	//DefaultCaptureRetention how long the captures of finished jobs are kept
+	DefaultCaptureRetention = 7 * 24 * time.Hour
+
+	//OutputStdout and OutputStderr are the names of the captured streams
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+
+	capturePrunePeriod = time.Hour
+	//tailChunkSize size of the blocks read from the end of a file to get its last lines
+	tailChunkSize = 64 * 1024
+)
+
+/*
+outputCapture writes the full stdout and stderr of the jobs to files. Each job stream is written to
+<dir>/<job-id>.<stream>, once the file reaches maxSize it's rotated to <job-id>.<stream>.1 and so on, only the last
+maxFiles rotated files are kept.
+*/
+type outputCapture struct {
+	dir       string
+	maxSize   int64
+	maxFiles  int
+	retention time.Duration
+}
+
+/*
+CaptureOutput enables writing the full output of all jobs under dir. Zero values for maxSize, maxFiles and retention
+use the defaults. Must be called before the process manager runs.
+*/
+func (pm *PM) CaptureOutput(dir string, maxSize int64, maxFiles int, retention time.Duration) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if maxSize <= 0 {
+		maxSize = DefaultCaptureMaxSize
+	}
+
+	if maxFiles <= 0 {
+		maxFiles = DefaultCaptureMaxFiles
+	}
+
+	if retention <= 0 {
+		retention = DefaultCaptureRetention
+	}
+
+	pm.capture = &outputCapture{
+		dir:       dir,
+		maxSize:   maxSize,
+		maxFiles:  maxFiles,
+		retention: retention,
+	}
+
+	go pm.capture.pruner(pm)
+	return nil
+}
+
+func (c *outputCapture) name(id, stream string) string {
+	return fmt.Sprintf("%s.%s", url.QueryEscape(id), stream)
+}
+
+//path gets the path of the nth file of the job stream, 0 is the file currently written.
+func (c *outputCapture) path(id, stream string, n int) string {
+	name := c.name(id, stream)
+	if n > 0 {
+		name = fmt.Sprintf("%s.%d", name, n)
+	}
+
+	return path.Join(c.dir, name)
+}
+
+//files gets the existing files of the job stream, oldest first
+func (c *outputCapture) files(id, stream string) []string {
+	var files []string
+	for n := c.maxFiles; n >= 0; n-- {
+		p := c.path(id, stream, n)
+		if _, err := os.Stat(p); err == nil {
+			files = append(files, p)
+		}
+	}
+
+	return files
+}
+
+//jobOf gets the id of the job that owns the given capture file name
+func (c *outputCapture) jobOf(name string) (string, bool) {
+	if idx := strings.LastIndex(name, "."); idx > 0 {
+		if _, err := strconv.Atoi(name[idx+1:]); err == nil {
+			name = name[:idx]
+		}
+	}
+
+	for _, stream := range []string{OutputStdout, OutputStderr} {
+		if strings.HasSuffix(name, "."+stream) {
+			id, err := url.QueryUnescape(strings.TrimSuffix(name, "."+stream))
+			return id, err == nil
+		}
+	}
+
+	return "", false
+}
+
+func (c *outputCapture) prune(pm *PM) {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		log.Errorf("Failed to list captured outputs: %s", err)
+		return
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || time.Since(info.ModTime()) < c.retention {
+			continue
+		}
+
+		id, ok := c.jobOf(info.Name())
+		if !ok {
+			continue
+		}
+
+		if _, running := pm.Runner(id); running {
+			continue
+		}
+
+		if err := os.Remove(path.Join(c.dir, info.Name())); err != nil {
+			log.Errorf("Failed to remove captured output: %s", err)
+		}
+	}
+}
+
+func (c *outputCapture) pruner(pm *PM) {
+	for {
+		c.prune(pm)
+		<-time.After(capturePrunePeriod)
+	}
+}
+
+//captureWriter writes a single job stream
+type captureWriter struct {
+	capture *outputCapture
+	id      string
+	stream  string
+	file    *os.File
+	size    int64
+}
+
+func (w *captureWriter) open() error {
+	file, err := os.OpenFile(w.capture.path(w.id, w.stream, 0), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *captureWriter) rotate() error {
+	w.file.Close()
+	w.file = nil
+
+	c := w.capture
+	os.Remove(c.path(w.id, w.stream, c.maxFiles))
+	for n := c.maxFiles - 1; n >= 0; n-- {
+		if err := os.Rename(c.path(w.id, w.stream, n), c.path(w.id, w.stream, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return w.open()
+}
+
+func (w *captureWriter) WriteLine(line string) error {
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	if w.size > 0 && w.size+int64(len(line))+1 > w.capture.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := io.WriteString(w.file, line+"\n")
+	w.size += int64(n)
+	return err
+}
+
+func (w *captureWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Close()
+}
+
+//jobCapture captures the streams of a single job run
+type jobCapture struct {
+	writers map[int]*captureWriter
+	failed  bool
+}
+
+func (c *outputCapture) open(id string) *jobCapture {
+	return &jobCapture{
+		writers: map[int]*captureWriter{
+			stream.LevelStdout: {capture: c, id: id, stream: OutputStdout},
+			stream.LevelStderr: {capture: c, id: id, stream: OutputStderr},
+		},
+	}
+}
+
+//Write writes a line of the given stream level, messages of other levels are ignored.
+func (j *jobCapture) Write(level int, line string) {
+	w, ok := j.writers[level]
+	if !ok || j.failed {
+		return
+	}
+
+	if err := w.WriteLine(line); err != nil {
+		//don't flood the logs with the same error on every line
+		log.Errorf("Failed to capture output of job %s: %s", w.id, err)
+		j.failed = true
+	}
+}
+
+func (j *jobCapture) Close() {
+	for _, w := range j.writers {
+		w.Close()
+	}
+}
+
+func (pm *PM) captureFiles(id, stream string) ([]string, error) {
+	if pm.capture == nil {
+		return nil, fmt.Errorf("output capture is not enabled")
+	}
+
+	if stream != OutputStdout && stream != OutputStderr {
+		return nil, fmt.Errorf("invalid stream '%s'", stream)
+	}
+
+	files := pm.capture.files(id, stream)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no captured output for job '%s'", id)
+	}
+
+	return files, nil
+}
+
+/*
+ReadOutput reads length bytes starting at offset from the captured stream of a job. The rotated files are read as a
+single stream, the offset is relative to the start of the oldest kept file. The total size of the capture is returned
+with the data.
+*/
+func (pm *PM) ReadOutput(id, stream string, offset, length int64) ([]byte, int64, error) {
+	files, err := pm.captureFiles(id, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var readers []io.Reader
+	var size int64
+	for _, p := range files {
+		file, err := os.Open(p)
+		if err != nil {
+			//rotated in the meantime
+			continue
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			continue
+		}
+
+		size += info.Size()
+		readers = append(readers, io.LimitReader(file, info.Size()))
+	}
+
+	reader := io.MultiReader(readers...)
+	if _, err := io.CopyN(ioutil.Discard, reader, offset); err != nil && err != io.EOF {
+		return nil, size, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, length))
+	return data, size, err
+}
+
+//tailFile gets the last n lines of a file, the file is read backward so only its end is loaded
+func tailFile(p string, n int) ([]string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	//n lines are complete once the new line before the first of them is read
+	var data []byte
+	offset := info.Size()
+	for count := 0; offset > 0 && count <= n; {
+		size := int64(tailChunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+
+		count += bytes.Count(chunk, []byte("\n"))
+		data = append(chunk, data...)
+	}
+
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, nil
+}
+
+//TailOutput gets the last n lines of the captured stream of a job
+func (pm *PM) TailOutput(id, stream string, n int) ([]string, error) {
+	files, err := pm.captureFiles(id, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for i := len(files) - 1; i >= 0 && len(lines) < n; i-- {
+		fileLines, err := tailFile(files[i], n-len(lines))
+		if err != nil {
+			continue
+		}
+
+		lines = append(fileLines, lines...)
+	}
+
+	return lines, nil
+}
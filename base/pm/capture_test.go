@@ -0,0 +1,100 @@
+package pm
+
+import (
+	"fmt"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func newTestCapture(t *testing.T) (*PM, func()) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &PM{runners: make(map[string]Runner)}
+	m.capture = &outputCapture{
+		dir:       dir,
+		maxSize:   20,
+		maxFiles:  2,
+		retention: DefaultCaptureRetention,
+	}
+
+	return m, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCaptureRotation(t *testing.T) {
+	m, cleanup := newTestCapture(t)
+	defer cleanup()
+
+	capture := m.capture.open("job/1")
+	for i := 0; i < 10; i++ {
+		//each line is 8 bytes (with the new line), so 2 lines fit in a file
+		capture.Write(stream.LevelStdout, fmt.Sprintf("line-%02d", i))
+	}
+	capture.Write(stream.LevelStderr, "error")
+	capture.Close()
+
+	//current file + 2 rotated ones
+	assert.Len(t, m.capture.files("job/1", OutputStdout), 3)
+
+	lines, err := m.TailOutput("job/1", OutputStdout, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"line-07", "line-08", "line-09"}, lines)
+	}
+
+	data, size, err := m.ReadOutput("job/1", OutputStdout, 7, 14)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(6*8), size)
+		assert.Equal(t, "\nline-05\nline-", string(data))
+	}
+
+	lines, err = m.TailOutput("job/1", OutputStderr, 10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"error"}, lines)
+	}
+
+	_, err = m.TailOutput("job/2", OutputStdout, 10)
+	assert.Error(t, err)
+}
+
+func TestTailFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	//the file spans a few chunks, only the end of it must be read
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(file, "line-%05d\n", i)
+	}
+	file.Close()
+
+	lines, err := tailFile(file.Name(), 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"line-19997", "line-19998", "line-19999"}, lines)
+	}
+
+	lines, err = tailFile(file.Name(), 30000)
+	if assert.NoError(t, err) {
+		assert.Len(t, lines, 20000)
+		assert.Equal(t, "line-00000", lines[0])
+	}
+}
+
+func TestCaptureJobOf(t *testing.T) {
+	c := &outputCapture{}
+
+	id, ok := c.jobOf(c.name("job.1", OutputStderr) + ".3")
+	assert.True(t, ok)
+	assert.Equal(t, "job.1", id)
+
+	_, ok = c.jobOf("something-else")
+	assert.False(t, ok)
+}
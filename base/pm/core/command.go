@@ -5,22 +5,106 @@ import (
 	"fmt"
 )
 
+const (
+	//RestartPolicyNever never restart the command
+	RestartPolicyNever = "never"
+	//RestartPolicyOnFailure restart the command only if it exits with an error
+	RestartPolicyOnFailure = "on-failure"
+	//RestartPolicyAlways restart the command whatever the exit state is (unless killed)
+	RestartPolicyAlways = "always"
+)
+
 type Route string
 
 //Cmd is an executable command
 type Command struct {
-	ID              string           `json:"id"`
-	Command         string           `json:"command"`
-	Arguments       *json.RawMessage `json:"arguments"`
-	Queue           string           `json:"queue"`
-	StatsInterval   int              `json:"stats_interval,omitempty"`
-	MaxTime         int              `json:"max_time,omitempty"`
-	MaxRestart      int              `json:"max_restart,omitempty"`
-	RecurringPeriod int              `json:"recurring_period,omitempty"`
-	LogLevels       []int            `json:"log_levels,omitempty"`
-	Tags            string           `json:"tags"`
+	ID                string           `json:"id"`
+	Command           string           `json:"command"`
+	Arguments         *json.RawMessage `json:"arguments"`
+	Queue             string           `json:"queue"`
+	QueueConcurrency  int              `json:"queue_concurrency,omitempty"`
+	Priority          int              `json:"priority,omitempty"`
+	StatsInterval     int              `json:"stats_interval,omitempty"`
+	MaxTime           int              `json:"max_time,omitempty"`
+	IdleTimeout       int              `json:"idle_timeout,omitempty"`
+	FailureMatch      string           `json:"failure_match,omitempty"`
+	MaxRestart        int              `json:"max_restart,omitempty"`
+	RestartPolicy     string           `json:"restart_policy,omitempty"`
+	RestartBackoff    int              `json:"restart_backoff,omitempty"`
+	RestartMaxBackoff int              `json:"restart_max_backoff,omitempty"`
+	StopSignal        string           `json:"stop_signal,omitempty"`
+	StopTimeout       int              `json:"stop_timeout,omitempty"`
+	RecurringPeriod   int              `json:"recurring_period,omitempty"`
+	Schedule          string           `json:"schedule,omitempty"`
+	ScheduleJitter    int              `json:"schedule_jitter,omitempty"`
+	SkipIfRunning     bool             `json:"skip_if_running,omitempty"`
+	LogLevels         []int            `json:"log_levels,omitempty"`
+	Limits            *Limits          `json:"limits,omitempty"`
+	HealthCheck       *HealthCheck     `json:"health_check,omitempty"`
+	Signature         *Signature       `json:"signature,omitempty"`
+	Tags              string           `json:"tags"`
 
 	Route Route `json:"-"`
+	//Silent commands (internal jobs like the health probes) don't reach the message and result handlers and their
+	//output is not captured, the result is only returned to the caller that waits for the job
+	Silent bool `json:"-"`
+
+	//payload is the json the command was loaded from
+	payload []byte
+}
+
+//Limits are optional resource limits that are enforced on the command process (via cgroups)
+type Limits struct {
+	//CPU max number of cpus (can be fractions, 0.5 means half a cpu)
+	CPU float64 `json:"cpu,omitempty"`
+	//CPUShares relative cpu weight
+	CPUShares int `json:"cpu_shares,omitempty"`
+	//Memory max memory in bytes
+	Memory uint64 `json:"memory,omitempty"`
+	//Pids max number of processes/threads
+	Pids int `json:"pids,omitempty"`
+	//BlkioWeight relative block IO weight (10 to 1000)
+	BlkioWeight int `json:"blkio_weight,omitempty"`
+	//BlkioReadBps max read rate per device, keyed by 'major:minor'
+	BlkioReadBps map[string]uint64 `json:"blkio_read_bps,omitempty"`
+	//BlkioWriteBps max write rate per device, keyed by 'major:minor'
+	BlkioWriteBps map[string]uint64 `json:"blkio_write_bps,omitempty"`
+}
+
+//HealthCheck is a probe that checks if a running command is healthy, only one of Exec, TCP or HTTP must be set
+type HealthCheck struct {
+	//Exec runs the given command (name and arguments), the job is healthy if it exits with success
+	Exec []string `json:"exec,omitempty"`
+	//TCP the job is healthy if a connection can be established to the given address (host:port)
+	TCP string `json:"tcp,omitempty"`
+	//HTTP the job is healthy if a GET to the given url returns a 2xx or 3xx status
+	HTTP string `json:"http,omitempty"`
+	//Interval in seconds between 2 probes
+	Interval int `json:"interval,omitempty"`
+	//Timeout in seconds of a single probe
+	Timeout int `json:"timeout,omitempty"`
+	//FailureThreshold number of failed probes in a row after which the job is considered unhealthy
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+}
+
+//Validate makes sure exactly one probe is set
+func (h *HealthCheck) Validate() error {
+	probes := 0
+	if len(h.Exec) > 0 {
+		probes++
+	}
+	if h.TCP != "" {
+		probes++
+	}
+	if h.HTTP != "" {
+		probes++
+	}
+
+	if probes != 1 {
+		return fmt.Errorf("health check must have exactly one of exec, tcp or http")
+	}
+
+	return nil
 }
 
 type M map[string]interface{}
@@ -35,11 +119,52 @@ func MustArguments(args interface{}) *json.RawMessage {
 	return &raw
 }
 
+//ValidateRestartPolicy checks the restart policy name, an empty policy stands for the default one
+func ValidateRestartPolicy(policy string) error {
+	switch policy {
+	case "", RestartPolicyNever, RestartPolicyOnFailure, RestartPolicyAlways:
+		return nil
+	}
+
+	return fmt.Errorf("unknown restart policy '%s', expected %s, %s or %s", policy,
+		RestartPolicyNever, RestartPolicyOnFailure, RestartPolicyAlways)
+}
+
+//GetRestartPolicy gets the effective restart policy of the command. A command with no restart policy
+//and a max_restart is restarted on failure.
+func (cmd *Command) GetRestartPolicy() string {
+	if cmd.RestartPolicy != "" {
+		return cmd.RestartPolicy
+	}
+
+	if cmd.MaxRestart > 0 {
+		return RestartPolicyOnFailure
+	}
+
+	return RestartPolicyNever
+}
+
 //String represents cmd as a string
 func (cmd *Command) String() string {
 	return fmt.Sprintf("(%s# %s)", cmd.ID, cmd.Command)
 }
 
+//UnmarshalJSON loads the command and keeps the original json, so its signature can be verified
+func (cmd *Command) UnmarshalJSON(data []byte) error {
+	type plain Command
+	if err := json.Unmarshal(data, (*plain)(cmd)); err != nil {
+		return err
+	}
+
+	cmd.payload = append([]byte(nil), data...)
+	return nil
+}
+
+//Payload gets the json the command was loaded from, nil if the command was not loaded from json
+func (cmd *Command) Payload() []byte {
+	return cmd.payload
+}
+
 //LoadCmd loads cmd from json string.
 func LoadCmd(str []byte) (*Command, error) {
 	var cmd Command
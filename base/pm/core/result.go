@@ -13,6 +13,26 @@ const (
 	StateUnknownCmd = "UNKNOWN_CMD"
 	//StateDuplicateID dublicate id exit status
 	StateDuplicateID = "DUPILICATE_ID"
+	//StateCancelled the command was cancelled while waiting on its queue
+	StateCancelled = "CANCELLED"
+	//StateUnhealthy the command was stopped because its health check failed
+	StateUnhealthy = "UNHEALTHY"
+	//StateIdleTimeout the command was killed because it didn't output anything for idle_timeout seconds
+	StateIdleTimeout = "IDLE_TIMEOUT"
+	//StateFailureMatch the command was killed because its output matched the failure_match pattern
+	StateFailureMatch = "FAILURE_MATCH"
+	//StateInterrupted the command was received but core0 stopped before it answered it
+	StateInterrupted = "INTERRUPTED"
+	//StateUnauthorized the command was rejected by the policy of the sink it was received from
+	StateUnauthorized = "UNAUTHORIZED"
+)
+
+const (
+	//ResultFormatJSON and the other formats are the formats a command can emit its result data in
+	ResultFormatJSON = "json"
+	ResultFormatYAML = "yaml"
+	ResultFormatTOML = "toml"
+	ResultFormatHRD  = "hrd"
 )
 
 //JobResult represents a result of a job
@@ -27,6 +47,32 @@ type JobResult struct {
 	StartTime int64    `json:"starttime"`
 	Time      int64    `json:"time"`
 	Tags      string   `json:"tags"`
+	//Format the command emitted its result in, Data is always json
+	Format string `json:"format,omitempty"`
+	//RawData the result as emitted by the command, only set if it was converted to json
+	RawData string `json:"raw_data,omitempty"`
+	//ParseError is set if the result couldn't be converted to json, Data then holds the raw result as a json string
+	ParseError string `json:"parse_error,omitempty"`
+	//Exit is only set for commands that run an external process
+	Exit *ExitStatus `json:"exit,omitempty"`
+}
+
+//ExitStatus the exit status and resource usage of a process
+type ExitStatus struct {
+	//Code the exit code of the process, if terminated by a signal it's 128 + signal number
+	Code int `json:"code"`
+	//Signal the signal that terminated the process (if any)
+	Signal int `json:"signal,omitempty"`
+	//CoreDump is true if the process dumped a core
+	CoreDump bool `json:"core_dump,omitempty"`
+	//UserTime and SystemTime are the cpu time (in seconds) spent in user and kernel mode
+	UserTime   float64 `json:"user_time"`
+	SystemTime float64 `json:"system_time"`
+	//MaxRSS max resident set size in bytes
+	MaxRSS int64 `json:"max_rss"`
+	//InBlock and OutBlock number of blocks read and written by the file system
+	InBlock  int64 `json:"in_block"`
+	OutBlock int64 `json:"out_block"`
 }
 
 //NewBasicJobResult creates a new job result from command
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+//Signature is a detached signature of a command, made with the key KeyID
+type Signature struct {
+	KeyID string `json:"key_id"`
+	//Node the command is meant for, so it can't be replayed on another node
+	Node string `json:"node"`
+	//Timestamp when the command was signed (unix seconds)
+	Timestamp int64 `json:"timestamp"`
+	//Nonce a unique value, a command is only accepted once within the replay window
+	Nonce string `json:"nonce"`
+	//Value base64 encoded ed25519 signature of the canonical json of the command
+	Value string `json:"value"`
+}
+
+/*
+Canonical gets the canonical json of the command json payload, which is the signed message. It's the payload without
+the signature `value`, with sorted object keys, no white spaces, and no escaping beyond what json requires. Numbers
+are kept as they were written.
+*/
+func Canonical(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var cmd map[string]interface{}
+	if err := decoder.Decode(&cmd); err != nil {
+		return nil, err
+	}
+
+	if signature, ok := cmd["signature"].(map[string]interface{}); ok {
+		delete(signature, "value")
+	} else if _, ok := cmd["signature"]; ok {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(cmd); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		expr     string
+		from     string
+		expected string
+	}{
+		{"* * * * *", "2016-10-05 10:00", "2016-10-05 10:01"},
+		{"*/15 * * * *", "2016-10-05 10:01", "2016-10-05 10:15"},
+		{"30 2 * * *", "2016-10-05 10:00", "2016-10-06 02:30"},
+		{"0 0 1 * *", "2016-12-05 10:00", "2017-01-01 00:00"},
+		{"0 12 * * mon", "2016-10-05 10:00", "2016-10-10 12:00"},
+		{"0 12 * * 7", "2016-10-05 10:00", "2016-10-09 12:00"},
+		{"0 0 29 feb *", "2016-03-01 00:00", "2020-02-29 00:00"},
+		{"0 9-17/4 * * 1-5", "2016-10-07 17:30", "2016-10-10 09:00"},
+		{"0 0 13 * fri", "2016-10-05 10:00", "2016-10-07 00:00"},
+		{"@hourly", "2016-10-05 10:59", "2016-10-05 11:00"},
+		{"@weekly", "2016-10-05 10:00", "2016-10-09 00:00"},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if !assert.Nil(t, err, c.expr) {
+			continue
+		}
+
+		assert.Equal(t, date(c.expected), s.Next(date(c.from)), c.expr)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 feb *")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	assert.True(t, s.Next(date("2016-10-05 10:00")).IsZero())
+}
@@ -0,0 +1,180 @@
+package pm
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"sync"
+)
+
+type dispatchEntry struct {
+	cmd      *core.Command
+	internal bool
+	seq      uint64
+}
+
+/*
+dispatcher schedules the pushed commands. Commands are picked by priority (higher first), commands of the same
+priority are picked in turns from the different routes (sinks) so a flood of commands from one route can't starve
+the others.
+
+A command is only dispatched if the global jobs limit, and the limit of its route, allow it. Internal (builtin)
+commands bypass both limits.
+*/
+type dispatcher struct {
+	maxJobs int
+	limits  map[core.Route]int
+
+	pending map[core.Route][]*dispatchEntry
+	running map[*core.Command]core.Route
+	routes  map[core.Route]int
+	served  map[core.Route]uint64
+	seq     uint64
+
+	m    sync.Mutex
+	cond *sync.Cond
+}
+
+func newDispatcher(maxJobs int) *dispatcher {
+	d := &dispatcher{
+		maxJobs: maxJobs,
+		limits:  make(map[core.Route]int),
+		pending: make(map[core.Route][]*dispatchEntry),
+		running: make(map[*core.Command]core.Route),
+		routes:  make(map[core.Route]int),
+		served:  make(map[core.Route]uint64),
+	}
+
+	d.cond = sync.NewCond(&d.m)
+	return d
+}
+
+//setLimit sets the max number of concurrent jobs of a route, 0 means no limit (other than the global one)
+func (d *dispatcher) setLimit(route core.Route, limit int) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if limit <= 0 {
+		delete(d.limits, route)
+	} else {
+		d.limits[route] = limit
+	}
+
+	d.cond.Broadcast()
+}
+
+//push adds a command to the pending commands of its route
+func (d *dispatcher) push(cmd *core.Command, internal bool) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.seq++
+	entry := &dispatchEntry{cmd: cmd, internal: internal, seq: d.seq}
+	queue := d.pending[cmd.Route]
+
+	//keep the route queue sorted by priority, commands of the same priority are kept in arrival order.
+	i := len(queue)
+	for i > 0 && queue[i-1].cmd.Priority < cmd.Priority {
+		i--
+	}
+
+	queue = append(queue, nil)
+	copy(queue[i+1:], queue[i:])
+	queue[i] = entry
+	d.pending[cmd.Route] = queue
+
+	d.cond.Broadcast()
+}
+
+//candidate gets the index of the first command of the route that can be dispatched now, -1 if none.
+func (d *dispatcher) candidate(route core.Route) int {
+	queue := d.pending[route]
+	limit, limited := d.limits[route]
+	blocked := len(d.running) >= d.maxJobs || (limited && d.routes[route] >= limit)
+
+	for i, entry := range queue {
+		if entry.internal || !blocked {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//better checks if entry a should be dispatched before entry b
+func (d *dispatcher) better(a, b *dispatchEntry) bool {
+	if a.cmd.Priority != b.cmd.Priority {
+		return a.cmd.Priority > b.cmd.Priority
+	}
+
+	//same priority, the route that was served the longest time ago goes first.
+	sa, sb := d.served[a.cmd.Route], d.served[b.cmd.Route]
+	if sa != sb {
+		return sa < sb
+	}
+
+	return a.seq < b.seq
+}
+
+//next blocks until a command can be dispatched, the command is then considered running until done is called.
+func (d *dispatcher) next() *core.Command {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	for {
+		var best *dispatchEntry
+		var bestIndex int
+
+		for route := range d.pending {
+			i := d.candidate(route)
+			if i < 0 {
+				continue
+			}
+
+			entry := d.pending[route][i]
+			if best == nil || d.better(entry, best) {
+				best, bestIndex = entry, i
+			}
+		}
+
+		if best == nil {
+			d.cond.Wait()
+			continue
+		}
+
+		route := best.cmd.Route
+		queue := d.pending[route]
+		queue = append(queue[:bestIndex], queue[bestIndex+1:]...)
+		if len(queue) == 0 {
+			delete(d.pending, route)
+		} else {
+			d.pending[route] = queue
+		}
+
+		d.seq++
+		d.served[route] = d.seq
+
+		if !best.internal {
+			d.running[best.cmd] = route
+			d.routes[route]++
+		}
+
+		return best.cmd
+	}
+}
+
+//done releases the slot of a dispatched command
+func (d *dispatcher) done(cmd *core.Command) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	route, ok := d.running[cmd]
+	if !ok {
+		return
+	}
+
+	delete(d.running, cmd)
+	if d.routes[route]--; d.routes[route] <= 0 {
+		delete(d.routes, route)
+	}
+
+	d.cond.Broadcast()
+}
@@ -0,0 +1,79 @@
+package pm
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func cmd(id string, route string, priority int) *core.Command {
+	return &core.Command{ID: id, Route: core.Route(route), Priority: priority}
+}
+
+//tryNext gets the next dispatched command, or nil if none can be dispatched
+func tryNext(d *dispatcher) *core.Command {
+	ch := make(chan *core.Command, 1)
+	go func() {
+		ch <- d.next()
+	}()
+
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(100 * time.Millisecond):
+		//unblock the pending next call
+		d.push(cmd("unblock", "", 0), true)
+		<-ch
+		return nil
+	}
+}
+
+func TestDispatcherPriority(t *testing.T) {
+	d := newDispatcher(10)
+	d.push(cmd("low", "a", 0), false)
+	d.push(cmd("high", "a", 10), false)
+	d.push(cmd("mid", "a", 5), false)
+
+	for _, id := range []string{"high", "mid", "low"} {
+		assert.Equal(t, id, d.next().ID)
+	}
+}
+
+func TestDispatcherFairness(t *testing.T) {
+	d := newDispatcher(10)
+	d.push(cmd("a1", "a", 0), false)
+	d.push(cmd("a2", "a", 0), false)
+	d.push(cmd("a3", "a", 0), false)
+	d.push(cmd("b1", "b", 0), false)
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		ids = append(ids, d.next().ID)
+	}
+
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, ids)
+}
+
+func TestDispatcherLimits(t *testing.T) {
+	d := newDispatcher(2)
+	d.setLimit("a", 1)
+
+	a1 := cmd("a1", "a", 0)
+	d.push(a1, false)
+	d.push(cmd("a2", "a", 0), false)
+	d.push(cmd("b1", "b", 0), false)
+	d.push(cmd("b2", "b", 0), false)
+
+	assert.Equal(t, "a1", d.next().ID)
+	assert.Equal(t, "b1", d.next().ID)
+	//global limit reached
+	assert.Nil(t, tryNext(d))
+
+	//builtin commands bypass the limits
+	d.push(cmd("kill", "c", 0), true)
+	assert.Equal(t, "kill", d.next().ID)
+
+	d.done(a1)
+	assert.Equal(t, "a2", d.next().ID)
+}
@@ -0,0 +1,207 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/process"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//DefaultHealthInterval time between 2 health probes
+	DefaultHealthInterval = 10 * time.Second
+	//DefaultHealthTimeout max time a health probe can take before it's considered failed
+	DefaultHealthTimeout = 5 * time.Second
+	//DefaultHealthFailureThreshold number of consecutive failed probes after which the job is unhealthy
+	DefaultHealthFailureThreshold = 3
+
+	//HealthStarting no probe completed yet, HealthHealthy the last probe succeeded, HealthUnhealthy the failure
+	//threshold was reached
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+var probeSequence uint64
+
+//HealthStatus is the state of the health probe of a job
+type HealthStatus struct {
+	Status string `json:"status"`
+	//Failures number of consecutive failed probes
+	Failures int `json:"failures"`
+	//LastCheck time of the last probe (unix seconds)
+	LastCheck int64  `json:"last_check"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type healthProbe struct {
+	manager *PM
+	cmd     *core.Command
+	check   *core.HealthCheck
+
+	status HealthStatus
+	m      sync.Mutex
+}
+
+func newHealthProbe(manager *PM, cmd *core.Command) *healthProbe {
+	return &healthProbe{
+		manager: manager,
+		cmd:     cmd,
+		check:   cmd.HealthCheck,
+		status: HealthStatus{
+			Status: HealthStarting,
+		},
+	}
+}
+
+func (p *healthProbe) interval() time.Duration {
+	if p.check.Interval > 0 {
+		return time.Duration(p.check.Interval) * time.Second
+	}
+
+	return DefaultHealthInterval
+}
+
+func (p *healthProbe) timeout() time.Duration {
+	if p.check.Timeout > 0 {
+		return time.Duration(p.check.Timeout) * time.Second
+	}
+
+	return DefaultHealthTimeout
+}
+
+func (p *healthProbe) threshold() int {
+	if p.check.FailureThreshold > 0 {
+		return p.check.FailureThreshold
+	}
+
+	return DefaultHealthFailureThreshold
+}
+
+func (p *healthProbe) probeExec(ctx context.Context) error {
+	cmd := &core.Command{
+		ID:      fmt.Sprintf("%s-health-%d", p.cmd.ID, atomic.AddUint64(&probeSequence, 1)),
+		Command: process.CommandSystem,
+		Arguments: core.MustArguments(
+			process.SystemCommandArguments{
+				Name: p.check.Exec[0],
+				Args: p.check.Exec[1:],
+			},
+		),
+		//don't flood the logs with the probes output
+		Silent: true,
+	}
+
+	result, err := p.manager.RunCmdWait(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if result.State != core.StateSuccess {
+		return fmt.Errorf("probe exited with state %s: %s", result.State, result.Streams)
+	}
+
+	return nil
+}
+
+func (p *healthProbe) probeTCP(ctx context.Context) error {
+	conn, err := net.DialTimeout("tcp", p.check.TCP, p.timeout())
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (p *healthProbe) probeHTTP(ctx context.Context) error {
+	request, err := http.NewRequest("GET", p.check.HTTP, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 400 {
+		return fmt.Errorf("unexpected http status: %s", response.Status)
+	}
+
+	return nil
+}
+
+//probe runs a single check
+func (p *healthProbe) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+
+	switch {
+	case len(p.check.Exec) > 0:
+		return p.probeExec(ctx)
+	case p.check.TCP != "":
+		return p.probeTCP(ctx)
+	case p.check.HTTP != "":
+		return p.probeHTTP(ctx)
+	}
+
+	return fmt.Errorf("health check has no probe")
+}
+
+/*
+run probes the job every interval until the context is done. Once the probe fails threshold times in a row the
+error is sent over unhealthy, and the probing stops.
+*/
+func (p *healthProbe) run(ctx context.Context, unhealthy chan<- error) {
+	ticker := time.NewTicker(p.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := p.probe(ctx)
+		if ctx.Err() != nil {
+			//the job exited while probing
+			return
+		}
+
+		p.m.Lock()
+		p.status.LastCheck = time.Now().Unix()
+		if err == nil {
+			p.status.Status = HealthHealthy
+			p.status.Failures = 0
+			p.status.LastError = ""
+		} else {
+			log.Warningf("Health probe of %s failed: %s", p.cmd, err)
+			p.status.Failures++
+			p.status.LastError = err.Error()
+			if p.status.Failures >= p.threshold() {
+				p.status.Status = HealthUnhealthy
+			}
+		}
+		status := p.status
+		p.m.Unlock()
+
+		if status.Status == HealthUnhealthy {
+			unhealthy <- fmt.Errorf("health check failed %d times in a row: %s", status.Failures, status.LastError)
+			return
+		}
+	}
+}
+
+//Status gets the current probe status
+func (p *healthProbe) Status() HealthStatus {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.status
+}
@@ -0,0 +1,101 @@
+package pm
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func probe(check *core.HealthCheck) *healthProbe {
+	return newHealthProbe(&PM{}, &core.Command{ID: "probed", HealthCheck: check})
+}
+
+//closedAddress gets a local address nothing listens on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestHealthProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer listener.Close()
+
+	assert.Nil(t, probe(&core.HealthCheck{TCP: listener.Addr().String()}).probe(context.Background()))
+	assert.NotNil(t, probe(&core.HealthCheck{TCP: closedAddress(t)}).probe(context.Background()))
+}
+
+func TestHealthProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	assert.Nil(t, probe(&core.HealthCheck{HTTP: server.URL + "/health"}).probe(context.Background()))
+	assert.NotNil(t, probe(&core.HealthCheck{HTTP: server.URL + "/other"}).probe(context.Background()))
+	assert.NotNil(t, probe(&core.HealthCheck{HTTP: "http://" + closedAddress(t)}).probe(context.Background()))
+}
+
+func TestHealthFailureThreshold(t *testing.T) {
+	p := probe(&core.HealthCheck{TCP: closedAddress(t), Interval: 1, FailureThreshold: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unhealthy := make(chan error, 1)
+	start := time.Now()
+	go p.run(ctx, unhealthy)
+
+	select {
+	case err := <-unhealthy:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("probe didn't report the job as unhealthy")
+	}
+
+	//one failure is not enough
+	assert.True(t, time.Since(start) >= 2*time.Second)
+	status := p.Status()
+	assert.Equal(t, HealthUnhealthy, status.Status)
+	assert.Equal(t, 2, status.Failures)
+}
+
+func TestHealthProbeSilent(t *testing.T) {
+	var results, messages int
+	mgr := &PM{}
+	mgr.AddResultHandler(func(cmd *core.Command, result *core.JobResult) {
+		results++
+	})
+	mgr.AddMessageHandler(func(cmd *core.Command, msg *stream.Message) {
+		messages++
+	})
+
+	//the probes results must not reach the result store or the sinks
+	probe := &core.Command{ID: "probed-health-1", Silent: true}
+	mgr.resultCallback(probe, &core.JobResult{ID: probe.ID})
+	mgr.msgCallback(probe, &stream.Message{Level: stream.LevelStdout})
+	assert.Equal(t, 0, results)
+	assert.Equal(t, 0, messages)
+
+	job := &core.Command{ID: "probed"}
+	mgr.resultCallback(job, &core.JobResult{ID: job.ID})
+	mgr.msgCallback(job, &stream.Message{Level: stream.LevelStdout})
+	assert.Equal(t, 1, results)
+	assert.Equal(t, 1, messages)
+}
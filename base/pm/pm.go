@@ -1,6 +1,7 @@
 package pm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/g8os/core0/base/pm/core"
@@ -20,6 +21,10 @@ import (
 	"time"
 )
 
+const (
+	prSetChildSubreaper = 36
+)
+
 var (
 	log               = logging.MustGetLogger("pm")
 	UnknownCommandErr = errors.New("unkonw command")
@@ -42,23 +47,22 @@ type StatsFlushHandler func(stats *stats.Stats)
 
 //PM is the main process manager.
 type PM struct {
-	midMux  sync.Mutex
-	cmds    chan *core.Command
-	runners map[string]Runner
+	midMux     sync.Mutex
+	dispatcher *dispatcher
+	runners    map[string]Runner
 
 	runnersMux sync.Mutex
 
 	statsdes map[string]*stats.Statsd
-	maxJobs  int
-	jobsCond *sync.Cond
 
 	msgHandlers         []MessageHandler
 	resultHandlers      []ResultHandler
 	routeResultHandlers map[core.Route][]ResultHandler
 	statsFlushHandlers  []StatsFlushHandler
 	queueMgr            *cmdQueueManager
+	capture             *outputCapture
 
-	pids    map[int]chan *syscall.WaitStatus
+	pids    map[int]chan *process.ProcessState
 	pidsMux sync.Mutex
 }
 
@@ -67,20 +71,19 @@ var pm *PM
 //NewPM creates a new PM
 func InitProcessManager(maxJobs int) *PM {
 	pm = &PM{
-		cmds:     make(chan *core.Command),
-		runners:  make(map[string]Runner),
-		maxJobs:  maxJobs,
-		jobsCond: sync.NewCond(&sync.Mutex{}),
+		dispatcher: newDispatcher(maxJobs),
+		runners:    make(map[string]Runner),
 
 		msgHandlers:         make([]MessageHandler, 0, 3),
 		resultHandlers:      make([]ResultHandler, 0, 3),
 		routeResultHandlers: make(map[core.Route][]ResultHandler),
 		statsFlushHandlers:  make([]StatsFlushHandler, 0, 3),
-		queueMgr:            newCmdQueueManager(),
 
-		pids: make(map[int]chan *syscall.WaitStatus),
+		pids: make(map[int]chan *process.ProcessState),
 	}
 
+	pm.queueMgr = newCmdQueueManager(pm.PushCmd)
+
 	log.Infof("Process manager intialization completed")
 	return pm
 }
@@ -112,9 +115,15 @@ func saveMid(midfile string, mid uint32) {
 	ioutil.WriteFile(midfile, []byte(fmt.Sprintf("%d", mid)), 0644)
 }
 
-//RunCmd runs and manage command
+//PushCmd pushes a command for execution, commands are dispatched by priority and only if the jobs limits allow it.
+//Builtin commands bypass the jobs limits.
 func (pm *PM) PushCmd(cmd *core.Command) {
-	pm.cmds <- cmd
+	pm.dispatcher.push(cmd, IsBuiltIn(cmd))
+}
+
+//SetRouteMaxJobs sets the max number of concurrent jobs pushed from the given route (sink), 0 means no limit.
+func (pm *PM) SetRouteMaxJobs(route core.Route, maxJobs int) {
+	pm.dispatcher.setLimit(route, maxJobs)
 }
 
 /*
@@ -127,6 +136,35 @@ func (pm *PM) PushCmdToQueue(cmd *core.Command) {
 	pm.queueMgr.Push(cmd)
 }
 
+//Queues gets the state of all the command queues
+func (pm *PM) Queues() []QueueInfo {
+	return pm.queueMgr.List()
+}
+
+//CancelQueued cancels a command that is still waiting on its queue, the command gets a CANCELLED result.
+func (pm *PM) CancelQueued(id string) error {
+	cmd, err := pm.queueMgr.Cancel(id)
+	if err != nil {
+		return err
+	}
+
+	result := core.NewBasicJobResult(cmd)
+	result.State = core.StateCancelled
+	pm.resultCallback(cmd, result)
+
+	return nil
+}
+
+//PauseQueue stops starting the commands of the given queue, until it's resumed
+func (pm *PM) PauseQueue(name string) {
+	pm.queueMgr.Pause(name)
+}
+
+//ResumeQueue resumes a paused queue
+func (pm *PM) ResumeQueue(name string) error {
+	return pm.queueMgr.Resume(name)
+}
+
 //AddMessageHandler adds handlers for messages that are captured from sub processes. Logger can use this to
 //process messages
 func (pm *PM) AddMessageHandler(handler MessageHandler) {
@@ -194,57 +232,91 @@ func (pm *PM) RunCmd(cmd *core.Command, hooks ...RunnerHook) (Runner, error) {
 	return runner, nil
 }
 
-func (pm *PM) processCmds() {
-	for {
-		pm.jobsCond.L.Lock()
-
-		for len(pm.runners) >= pm.maxJobs {
-			pm.jobsCond.Wait()
-		}
-		pm.jobsCond.L.Unlock()
+/*
+RunCmdWait runs the command and waits for its result. If the context is done before the command exits, the command
+is killed and the context error is returned.
+*/
+func (pm *PM) RunCmdWait(ctx context.Context, cmd *core.Command, hooks ...RunnerHook) (*core.JobResult, error) {
+	runner, err := pm.RunCmd(cmd, hooks...)
+	if err != nil {
+		return nil, err
+	}
 
-		var cmd *core.Command
+	done := make(chan *core.JobResult, 1)
+	go func() {
+		done <- runner.Wait()
+	}()
+
+	select {
+	case result := <-done:
+		return result, nil
+	case <-ctx.Done():
+		runner.Kill()
+		return nil, ctx.Err()
+	}
+}
 
-		//we have 2 possible sources of cmds.
-		//1- cmds that doesn't require waiting on a queue, those can run immediately
-		//2- cmds that were waiting on a queue (so they must execute serially)
-		select {
-		case cmd = <-pm.cmds:
-		case cmd = <-pm.queueMgr.Producer():
+func (pm *PM) processCmds() {
+	//commands that were waiting on a queue are pushed to the dispatcher by the queue manager once they reach the
+	//queue head.
+	for {
+		cmd := pm.dispatcher.next()
+		if _, err := pm.RunCmd(cmd); err != nil {
+			pm.dispatcher.done(cmd)
+			pm.queueMgr.Notify(cmd)
 		}
-
-		pm.RunCmd(cmd)
 	}
 }
 
 func (pm *PM) processWait() {
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGCHLD)
 	for _ = range ch {
-		var status syscall.WaitStatus
-		var rusage syscall.Rusage
-
-		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, &rusage)
-		if err != nil {
-			log.Errorf("Wait error: %s", err)
-			continue
+		//SIGCHLD signals are coalesced, a single signal can stand for many exited children.
+		for pm.reap() {
 		}
+	}
+}
 
-		//Avoid reading the process state before the Register call is complete.
-		pm.pidsMux.Lock()
-		ch, ok := pm.pids[pid]
-		pm.pidsMux.Unlock()
-
-		if ok {
-			go func() {
-				ch <- &status
-				close(ch)
-				pm.pidsMux.Lock()
-				defer pm.pidsMux.Unlock()
-				delete(pm.pids, pid)
-			}()
-		}
+//reap collects the state of one exited child, it returns false if no more children can be reaped.
+func (pm *PM) reap() bool {
+	var status syscall.WaitStatus
+	var rusage syscall.Rusage
+
+	pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, &rusage)
+	if err == syscall.EINTR {
+		return true
+	} else if err == syscall.ECHILD {
+		return false
+	} else if err != nil {
+		log.Errorf("Wait error: %s", err)
+		return false
+	} else if pid <= 0 {
+		//remaining children are still running
+		return false
+	}
+
+	//Avoid reading the process state before the Register call is complete.
+	pm.pidsMux.Lock()
+	ch, ok := pm.pids[pid]
+	pm.pidsMux.Unlock()
+
+	if !ok {
+		//a process that was not started by the process manager, most probably an orphan
+		//that got re-parented to us.
+		log.Infof("Reaped orphan process %d (exit status: %d)", pid, status.ExitStatus())
+		return true
 	}
+
+	go func() {
+		ch <- &process.ProcessState{Status: status, Rusage: rusage}
+		close(ch)
+		pm.pidsMux.Lock()
+		defer pm.pidsMux.Unlock()
+		delete(pm.pids, pid)
+	}()
+
+	return true
 }
 
 func (pm *PM) Register(g process.GetPID) error {
@@ -255,18 +327,26 @@ func (pm *PM) Register(g process.GetPID) error {
 		return err
 	}
 
-	ch := make(chan *syscall.WaitStatus)
+	ch := make(chan *process.ProcessState)
 	pm.pids[pid] = ch
 
 	return nil
 }
 
-func (pm *PM) WaitPID(pid int) *syscall.WaitStatus {
+func (pm *PM) WaitPID(pid int) *process.ProcessState {
 	return <-pm.pids[pid]
 }
 
 //Run starts the process manager.
 func (pm *PM) Run() {
+	if os.Getpid() != 1 {
+		//orphans are re-parented to PID 1, unless we are a subreaper, in which case they are
+		//re-parented to us and get reaped by the process manager.
+		if _, _, e := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); e != 0 {
+			log.Errorf("Failed to become a subreaper: %s", e)
+		}
+	}
+
 	//process and start all commands according to args.
 	go pm.processWait()
 	go pm.processCmds()
@@ -278,6 +358,18 @@ start in order, but will also make sure a service won't start until it's depende
 running.
 */
 func (pm *PM) RunSlice(slice settings.StartupSlice) {
+	state := pm.StartSlice(slice)
+
+	//wait for the full slice to run
+	log.Infof("Waiting for the slice to boot")
+	state.WaitAll()
+}
+
+/*
+StartSlice starts a slice of processes like RunSlice but doesn't wait for them. Each process key is released on the
+returned state machine once the process is running (true), or if it failed to start (false).
+*/
+func (pm *PM) StartSlice(slice settings.StartupSlice) StateMachine {
 	var all []string
 	for _, startup := range slice {
 		all = append(all, startup.Key())
@@ -291,9 +383,14 @@ func (pm *PM) RunSlice(slice settings.StartupSlice) {
 		}
 
 		cmd := &core.Command{
-			ID:        startup.Key(),
-			Command:   startup.Name,
-			Arguments: core.MustArguments(startup.Args),
+			ID:                startup.Key(),
+			Command:           startup.Name,
+			Arguments:         core.MustArguments(startup.Args),
+			RestartPolicy:     startup.RestartPolicy,
+			MaxRestart:        startup.MaxRestart,
+			RestartBackoff:    startup.RestartBackoff,
+			RestartMaxBackoff: startup.RestartMaxBackoff,
+			HealthCheck:       startup.HealthCheck,
 		}
 
 		go func(up settings.Startup, c *core.Command) {
@@ -334,8 +431,9 @@ func (pm *PM) RunSlice(slice settings.StartupSlice) {
 					},
 				})
 
-				pm.RunCmd(c, hooks...)
-
+				if _, err := pm.RunCmd(c, hooks...); err != nil {
+					state.Release(c.ID, false)
+				}
 			} else {
 				log.Errorf("Can't start %s because one of the dependencies failed", c)
 				state.Release(c.ID, false)
@@ -343,9 +441,7 @@ func (pm *PM) RunSlice(slice settings.StartupSlice) {
 		}(startup, cmd)
 	}
 
-	//wait for the full slice to run
-	log.Infof("Waiting for the slice to boot")
-	state.WaitAll()
+	return state
 }
 
 func (pm *PM) cleanUp(runner Runner) {
@@ -353,8 +449,8 @@ func (pm *PM) cleanUp(runner Runner) {
 	delete(pm.runners, runner.Command().ID)
 	pm.runnersMux.Unlock()
 
+	pm.dispatcher.done(runner.Command())
 	pm.queueMgr.Notify(runner.Command())
-	pm.jobsCond.Broadcast()
 }
 
 //Processes returs a list of running processes
@@ -362,6 +458,28 @@ func (pm *PM) Runners() map[string]Runner {
 	return pm.runners
 }
 
+//RunnerList gets a copy of the running jobs, safe to use while jobs start and exit
+func (pm *PM) RunnerList() []Runner {
+	pm.runnersMux.Lock()
+	defer pm.runnersMux.Unlock()
+
+	runners := make([]Runner, 0, len(pm.runners))
+	for _, runner := range pm.runners {
+		runners = append(runners, runner)
+	}
+
+	return runners
+}
+
+//Runner gets the runner of the job with the given ID
+func (pm *PM) Runner(id string) (Runner, bool) {
+	pm.runnersMux.Lock()
+	defer pm.runnersMux.Unlock()
+
+	runner, ok := pm.runners[id]
+	return runner, ok
+}
+
 //Killall kills all running processes.
 func (pm *PM) Killall() {
 	pm.runnersMux.Lock()
@@ -380,7 +498,26 @@ func (pm *PM) Kill(cmdID string) {
 	}
 }
 
+//Signal sends a signal to a process by the cmd ID, unlike Kill the process is not considered killed.
+func (pm *PM) Signal(cmdID string, sig syscall.Signal) error {
+	v, o := pm.Runner(cmdID)
+	if !o {
+		return fmt.Errorf("process with id '%s' doesn't exist", cmdID)
+	}
+
+	ps := v.Process()
+	if ps == nil {
+		return fmt.Errorf("process with id '%s' is not running", cmdID)
+	}
+
+	return ps.Signal(sig)
+}
+
 func (pm *PM) msgCallback(cmd *core.Command, msg *stream.Message) {
+	if cmd.Silent {
+		return
+	}
+
 	if len(cmd.LogLevels) > 0 && !utils.In(cmd.LogLevels, msg.Level) {
 		return
 	}
@@ -393,6 +530,10 @@ func (pm *PM) msgCallback(cmd *core.Command, msg *stream.Message) {
 }
 
 func (pm *PM) resultCallback(cmd *core.Command, result *core.JobResult) {
+	if cmd.Silent {
+		return
+	}
+
 	result.Tags = cmd.Tags
 	//NOTE: we always force the real gid and nid on the result.
 
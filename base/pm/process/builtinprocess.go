@@ -1,15 +1,19 @@
 package process
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/stream"
+	"syscall"
 )
 
 /*
-Runable represents a runnable built in function that can be managed by the process manager.
+Runable represents a runnable built in function that can be managed by the process manager. The context is cancelled
+when the process is killed (or times out), long running functions must return as soon as possible once that happens.
 */
-type Runnable func(*core.Command) (interface{}, error)
+type Runnable func(context.Context, *core.Command) (interface{}, error)
 
 /*
 internalProcess implements a Procss interface and represents an internal (go) process that can be managed by the process manager
@@ -17,12 +21,22 @@ internalProcess implements a Procss interface and represents an internal (go) pr
 type internalProcess struct {
 	runnable Runnable
 	cmd      *core.Command
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+type runnableResult struct {
+	value interface{}
+	err   error
 }
 
 func NewInternalProcess(cmd *core.Command, runnable Runnable) Process {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &internalProcess{
 		runnable: runnable,
 		cmd:      cmd,
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
@@ -53,7 +67,23 @@ func (process *internalProcess) Run() (<-chan *stream.Message, error) {
 
 	go func(channel chan *stream.Message) {
 		defer close(channel)
-		value, err := process.runnable(process.cmd)
+		defer process.cancel()
+
+		done := make(chan runnableResult, 1)
+		go func() {
+			value, err := process.runnable(process.ctx, process.cmd)
+			done <- runnableResult{value, err}
+		}()
+
+		var result runnableResult
+		select {
+		case result = <-done:
+		case <-process.ctx.Done():
+			//don't wait for functions that doesn't honor the context
+			result.err = process.ctx.Err()
+		}
+
+		value, err := result.value, result.err
 		msg := stream.Message{
 			Level: stream.LevelResultJSON,
 		}
@@ -79,10 +109,17 @@ func (process *internalProcess) Run() (<-chan *stream.Message, error) {
 }
 
 /*
-Kill kills internal process (not implemented)
+Kill kills internal process by cancelling its context
 */
 func (process *internalProcess) Kill() {
-	//you can't kill an internal process.
+	process.cancel()
+}
+
+/*
+Signal signals internal process (not supported)
+*/
+func (process *internalProcess) Signal(sig syscall.Signal) error {
+	return fmt.Errorf("can't signal an internal process")
 }
 
 /*
@@ -0,0 +1,45 @@
+package process
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInternalProcessKill(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	process := NewInternalProcess(&core.Command{ID: "test"}, func(ctx context.Context, cmd *core.Command) (interface{}, error) {
+		//doesn't honor the context
+		<-block
+		return nil, nil
+	})
+
+	channel, err := process.Run()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	process.Kill()
+
+	var last *stream.Message
+	timeout := time.After(time.Second)
+loop:
+	for {
+		select {
+		case msg, ok := <-channel:
+			if !ok {
+				break loop
+			}
+			last = msg
+		case <-timeout:
+			t.Fatal("internal process didn't exit after kill")
+		}
+	}
+
+	assert.Equal(t, stream.MessageExitError, last)
+}
@@ -0,0 +1,284 @@
+package process
+
+import (
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	//CGroupRoot where the cgroup hierarchies are mounted (see conf.extra/cgroup.toml)
+	CGroupRoot = "/sys/fs/cgroup"
+	//CGroupBase is the parent group of all the groups created by the process manager
+	CGroupBase = "g8os"
+
+	cgroupCPU     = "cpu"
+	cgroupCPUAcct = "cpuacct"
+	cgroupMemory  = "memory"
+	cgroupPids    = "pids"
+	cgroupBlkio   = "blkio"
+
+	cfsPeriod = 100000
+
+	cgroupRemoveRetries = 10
+	cgroupRemoveDelay   = 100 * time.Millisecond
+)
+
+var (
+	//cgroupRoot is CGroupRoot, tests run against a different hierarchy
+	cgroupRoot = CGroupRoot
+)
+
+/*
+cgroup is a per process control group that spans multiple subsystems. It's used to
+enforce the command limits, and to account for the usage of the process and all of its
+children.
+*/
+type cgroup struct {
+	name       string
+	subsystems []string
+
+	lastUsage  uint64
+	lastSample time.Time
+}
+
+/*
+cgroupName gets the group name of a job, the job id is escaped (like the capture files) so a client supplied id
+can't point outside of the CGroupBase group.
+*/
+func cgroupName(id string) (string, error) {
+	name := url.QueryEscape(id)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid cgroup name '%s'", id)
+	}
+
+	return name, nil
+}
+
+//newCGroup creates a new control group for the given job id, and applies the given limits.
+func newCGroup(id string, limits *core.Limits) (*cgroup, error) {
+	name, err := cgroupName(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cg := &cgroup{
+		name: name,
+	}
+
+	//accounting subsystems, used for stats if available.
+	required := map[string]bool{
+		cgroupCPUAcct: false,
+		cgroupMemory:  limits.Memory > 0,
+	}
+
+	if limits.CPU > 0 || limits.CPUShares > 0 {
+		required[cgroupCPU] = true
+	}
+	if limits.Pids > 0 {
+		required[cgroupPids] = true
+	}
+	if limits.BlkioWeight > 0 || len(limits.BlkioReadBps) > 0 || len(limits.BlkioWriteBps) > 0 {
+		required[cgroupBlkio] = true
+	}
+
+	for subsys, must := range required {
+		if _, err := os.Stat(path.Join(cgroupRoot, subsys)); err != nil {
+			if must {
+				cg.Remove()
+				return nil, fmt.Errorf("cgroup subsystem '%s' is not available", subsys)
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(cg.path(subsys), 0755); err != nil {
+			cg.Remove()
+			return nil, err
+		}
+
+		cg.subsystems = append(cg.subsystems, subsys)
+	}
+
+	if err := cg.apply(limits); err != nil {
+		cg.Remove()
+		return nil, err
+	}
+
+	return cg, nil
+}
+
+func (cg *cgroup) path(subsys string) string {
+	return path.Join(cgroupRoot, subsys, CGroupBase, cg.name)
+}
+
+func (cg *cgroup) has(subsys string) bool {
+	for _, s := range cg.subsystems {
+		if s == subsys {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cg *cgroup) write(subsys string, file string, value interface{}) error {
+	return ioutil.WriteFile(path.Join(cg.path(subsys), file), []byte(fmt.Sprint(value)), 0644)
+}
+
+func (cg *cgroup) read(subsys string, file string) (string, error) {
+	data, err := ioutil.ReadFile(path.Join(cg.path(subsys), file))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (cg *cgroup) apply(limits *core.Limits) error {
+	if limits.CPU > 0 {
+		if err := cg.write(cgroupCPU, "cpu.cfs_period_us", cfsPeriod); err != nil {
+			return err
+		}
+		if err := cg.write(cgroupCPU, "cpu.cfs_quota_us", int64(limits.CPU*cfsPeriod)); err != nil {
+			return err
+		}
+	}
+
+	if limits.CPUShares > 0 {
+		if err := cg.write(cgroupCPU, "cpu.shares", limits.CPUShares); err != nil {
+			return err
+		}
+	}
+
+	if limits.Memory > 0 {
+		if err := cg.write(cgroupMemory, "memory.limit_in_bytes", limits.Memory); err != nil {
+			return err
+		}
+	}
+
+	if limits.Pids > 0 {
+		if err := cg.write(cgroupPids, "pids.max", limits.Pids); err != nil {
+			return err
+		}
+	}
+
+	if limits.BlkioWeight > 0 {
+		if err := cg.write(cgroupBlkio, "blkio.weight", limits.BlkioWeight); err != nil {
+			return err
+		}
+	}
+
+	for dev, bps := range limits.BlkioReadBps {
+		if err := cg.write(cgroupBlkio, "blkio.throttle.read_bps_device", fmt.Sprintf("%s %d", dev, bps)); err != nil {
+			return err
+		}
+	}
+
+	for dev, bps := range limits.BlkioWriteBps {
+		if err := cg.write(cgroupBlkio, "blkio.throttle.write_bps_device", fmt.Sprintf("%s %d", dev, bps)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//procs gets the files a process writes its pid to, to join the group (in all subsystems)
+func (cg *cgroup) procs() []string {
+	var files []string
+	for _, subsys := range cg.subsystems {
+		files = append(files, path.Join(cg.path(subsys), "cgroup.procs"))
+	}
+
+	return files
+}
+
+//Pids gets the pids of all the processes in the group
+func (cg *cgroup) Pids() []int {
+	if len(cg.subsystems) == 0 {
+		return nil
+	}
+
+	value, err := cg.read(cg.subsystems[0], "cgroup.procs")
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, line := range strings.Fields(value) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids
+}
+
+//Stats fills the given stats object from the group accounting
+func (cg *cgroup) Stats(stats *ProcessStats) {
+	if cg.has(cgroupCPUAcct) {
+		if value, err := cg.read(cgroupCPUAcct, "cpuacct.usage"); err == nil {
+			usage, _ := strconv.ParseUint(value, 10, 64)
+			now := time.Now()
+			if !cg.lastSample.IsZero() && usage >= cg.lastUsage {
+				elapsed := now.Sub(cg.lastSample).Nanoseconds()
+				if elapsed > 0 {
+					stats.CPU = float64(usage-cg.lastUsage) / float64(elapsed) * 100
+				}
+			}
+			cg.lastUsage = usage
+			cg.lastSample = now
+		}
+	}
+
+	if cg.has(cgroupMemory) {
+		if value, err := cg.read(cgroupMemory, "memory.stat"); err == nil {
+			for _, line := range strings.Split(value, "\n") {
+				var key string
+				var v uint64
+				if _, err := fmt.Sscanf(line, "%s %d", &key, &v); err != nil {
+					continue
+				}
+				switch key {
+				case "total_rss":
+					stats.RSS = v
+				case "total_swap":
+					stats.Swap = v
+				}
+			}
+		}
+	}
+}
+
+/*
+Remove deletes the group from all subsystems. Processes left in the group once the job is over (ex: daemonized
+children) are killed first, otherwise the group can't be removed.
+*/
+func (cg *cgroup) Remove() {
+	for i := 0; i < cgroupRemoveRetries; i++ {
+		pids := cg.Pids()
+		if len(pids) == 0 {
+			break
+		}
+
+		log.Warningf("Killing %d processes left in cgroup '%s'", len(pids), cg.name)
+		for _, pid := range pids {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+
+		time.Sleep(cgroupRemoveDelay)
+	}
+
+	for _, subsys := range cg.subsystems {
+		if err := os.Remove(cg.path(subsys)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to remove cgroup '%s': %s", cg.path(subsys), err)
+		}
+	}
+}
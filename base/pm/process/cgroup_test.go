@@ -0,0 +1,128 @@
+package process
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+//testTable waits the processes itself, in place of the process manager reaper
+type testTable struct{}
+
+func (testTable) Register(g GetPID) error {
+	_, err := g()
+	return err
+}
+
+func (testTable) WaitPID(pid int) *ProcessState {
+	var state ProcessState
+	syscall.Wait4(pid, &state.Status, 0, &state.Rusage)
+	return &state
+}
+
+//runSystem runs a system command to the end, it returns the stdout lines and the exit message
+func runSystem(t *testing.T, cmd *core.Command) ([]string, *stream.Message) {
+	ps := NewSystemProcess(testTable{}, cmd)
+	channel, err := ps.Run()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	var lines []string
+	var exit *stream.Message
+	for msg := range channel {
+		switch msg.Level {
+		case stream.LevelStdout:
+			lines = append(lines, msg.Message)
+		case stream.LevelExitState:
+			exit = msg
+		}
+	}
+
+	return lines, exit
+}
+
+func requireCGroup(t *testing.T, subsys string) {
+	dir := path.Join(cgroupRoot, subsys, CGroupBase)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Skipf("cgroup %s is not available: %s", subsys, err)
+	}
+}
+
+func TestCGroupName(t *testing.T) {
+	for _, id := range []string{"../../x", "a/b", "job"} {
+		name, err := cgroupName(id)
+		if assert.Nil(t, err, id) {
+			assert.Equal(t, path.Join("/base", name), path.Join("/base", path.Clean(name)), id)
+			assert.NotContains(t, name, "/", id)
+		}
+	}
+
+	for _, id := range []string{"", ".", ".."} {
+		_, err := cgroupName(id)
+		assert.NotNil(t, err, id)
+	}
+}
+
+func TestCGroupLimits(t *testing.T) {
+	requireCGroup(t, cgroupPids)
+
+	cmd := &core.Command{
+		ID:      "../cgroup-limits",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name: "sh",
+			//the forked cat must already be in the group
+			Args: []string{"-c", "cat /proc/self/cgroup; cat /sys/fs/cgroup/pids/g8os/.*cgroup-limits/pids.max"},
+		}),
+		Limits: &core.Limits{Pids: 10},
+	}
+
+	lines, exit := runSystem(t, cmd)
+	assert.Equal(t, stream.MessageExitSuccess, exit)
+	assert.Contains(t, lines, "10")
+
+	name, _ := cgroupName(cmd.ID)
+	var joined bool
+	for _, line := range lines {
+		if strings.HasSuffix(line, ":pids:/"+CGroupBase+"/"+name) {
+			joined = true
+		}
+	}
+	assert.True(t, joined, "process is not in the job cgroup: %v", lines)
+
+	_, err := os.Stat(path.Join(cgroupRoot, cgroupPids, CGroupBase, name))
+	assert.True(t, os.IsNotExist(err), "cgroup was not removed")
+}
+
+func TestCGroupLeftovers(t *testing.T) {
+	requireCGroup(t, cgroupPids)
+
+	cmd := &core.Command{
+		ID:      "cgroup-leftovers",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name: "sh",
+			Args: []string{"-c", "setsid sleep 100 >/dev/null 2>&1 < /dev/null & echo $!"},
+		}),
+		Limits: &core.Limits{Pids: 10},
+	}
+
+	lines, exit := runSystem(t, cmd)
+	assert.Equal(t, stream.MessageExitSuccess, exit)
+
+	_, err := os.Stat(path.Join(cgroupRoot, cgroupPids, CGroupBase, cmd.ID))
+	assert.True(t, os.IsNotExist(err), "cgroup was not removed")
+
+	if assert.Len(t, lines, 1) {
+		data, _ := ioutil.ReadFile(path.Join("/proc", lines[0], "stat"))
+		//the killed sleep is gone, or a zombie until it's reaped by init
+		assert.True(t, len(data) == 0 || strings.Contains(string(data), ") Z "), string(data))
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/stream"
 	psutils "github.com/shirou/gopsutil/process"
+	"io"
+	"os"
 	"os/exec"
 	"syscall"
 )
@@ -16,6 +18,10 @@ type ContainerCommandArguments struct {
 	Args   []string          `json:"args"`
 	Env    map[string]string `json:"env"`
 	Chroot string            `json:"chroot"`
+	//TTY runs the process attached to a pseudo terminal of the given size (Rows x Cols)
+	TTY  bool   `json:"tty,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
 }
 
 type containerProcessImpl struct {
@@ -23,14 +29,19 @@ type containerProcessImpl struct {
 	args    ContainerCommandArguments
 	pid     int
 	process *psutils.Process
+	exited  chan struct{}
+	tty     *os.File
+	exit    *core.ExitStatus
+	tracker tracker
 
 	table PIDTable
 }
 
 func NewContainerProcess(table PIDTable, cmd *core.Command) Process {
 	process := &containerProcessImpl{
-		cmd:   cmd,
-		table: table,
+		cmd:    cmd,
+		exited: make(chan struct{}),
+		table:  table,
 	}
 
 	json.Unmarshal(*cmd.Arguments, &process.args)
@@ -43,9 +54,44 @@ func (process *containerProcessImpl) Command() *core.Command {
 
 func (process *containerProcessImpl) Kill() {
 	//should force system process to exit.
-	if process.process != nil {
-		process.process.Kill()
+	if process.pid <= 0 {
+		return
 	}
+
+	stop(process.cmd, process.pid, process.exited, escaped(process.pid, descendants(process.pid))...)
+}
+
+//Resize changes the window size of the process terminal
+func (process *containerProcessImpl) Resize(rows, cols uint16) error {
+	if process.tty == nil {
+		return fmt.Errorf("process has no tty")
+	}
+
+	return setWindowSize(process.tty, rows, cols)
+}
+
+func (process *containerProcessImpl) closeTTY() {
+	if process.tty != nil {
+		process.tty.Close()
+	}
+}
+
+//ExitStatus gets the exit status of the process
+func (process *containerProcessImpl) ExitStatus() *core.ExitStatus {
+	select {
+	case <-process.exited:
+		return process.exit
+	default:
+		return nil
+	}
+}
+
+func (process *containerProcessImpl) Signal(sig syscall.Signal) error {
+	if process.pid <= 0 {
+		return fmt.Errorf("process is not running")
+	}
+
+	return syscall.Kill(process.pid, sig)
 }
 
 //GetStats gets stats of an external process
@@ -77,6 +123,7 @@ func (process *containerProcessImpl) GetStats() *ProcessStats {
 	}
 
 	stats.Debug = fmt.Sprintf("%d", process.process.Pid)
+	process.tracker.add(descendants(process.pid), &stats)
 
 	return &stats
 }
@@ -96,17 +143,39 @@ func (process *containerProcessImpl) Run() (<-chan *stream.Message, error) {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", k, v))
 	}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
-	}
+	var stdout, stderr io.Reader
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
+	if process.args.TTY {
+		master, slave, err := openPty(process.args.Rows, process.args.Cols)
+		if err != nil {
+			return nil, err
+		}
+
+		//the slave end is only needed by the child
+		defer slave.Close()
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		//a new session is needed to acquire the controlling terminal, the session
+		//leader is also a process group leader.
+		cmd.SysProcAttr.Setpgid = false
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+
+		process.tty = master
+		stdout = &ptyReader{master}
+	} else {
+		var err error
+		if stdout, err = cmd.StdoutPipe(); err != nil {
+			return nil, err
+		}
+
+		if stderr, err = cmd.StderrPipe(); err != nil {
+			return nil, err
+		}
 	}
 
-	err = process.table.Register(func() (int, error) {
+	err := process.table.Register(func() (int, error) {
 		err := cmd.Start()
 		if err != nil {
 			return 0, err
@@ -117,6 +186,7 @@ func (process *containerProcessImpl) Run() (<-chan *stream.Message, error) {
 
 	if err != nil {
 		log.Errorf("Failed to start process(%s): %s", process.cmd.ID, err)
+		process.closeTTY()
 		return nil, err
 	}
 
@@ -137,24 +207,25 @@ func (process *containerProcessImpl) Run() (<-chan *stream.Message, error) {
 	}
 
 	// start consuming outputs.
-	outConsumer := stream.NewConsumer(stdout, 1)
-	outConsumer.Consume(msgInterceptor)
-
-	errConsumer := stream.NewConsumer(stderr, 2)
-	errConsumer.Consume(msgInterceptor)
+	consumers := consume(msgInterceptor, stdout, stderr)
 
 	go func(channel chan *stream.Message) {
 		//make sure all outputs are closed before waiting for the process
 		//to exit.
 		defer close(channel)
 
-		<-outConsumer.Signal()
-		<-errConsumer.Signal()
+		for _, consumer := range consumers {
+			<-consumer.Signal()
+		}
+
 		state := process.table.WaitPID(process.pid)
+		process.exit = state.ExitStatus()
+		close(process.exited)
+		process.closeTTY()
 
-		log.Infof("Process %s exited with state: %d", process.cmd, state.ExitStatus())
+		log.Infof("Process %s exited with state: %d", process.cmd, process.exit.Code)
 
-		if state.ExitStatus() == 0 {
+		if state.Status.ExitStatus() == 0 {
 			channel <- stream.MessageExitSuccess
 		} else {
 			channel <- stream.MessageExitError
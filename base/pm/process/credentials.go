@@ -0,0 +1,207 @@
+package process
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+var (
+	rlimits = map[string]int{
+		"cpu":        syscall.RLIMIT_CPU,
+		"fsize":      syscall.RLIMIT_FSIZE,
+		"data":       syscall.RLIMIT_DATA,
+		"stack":      syscall.RLIMIT_STACK,
+		"core":       syscall.RLIMIT_CORE,
+		"rss":        5,
+		"nproc":      6,
+		"nofile":     syscall.RLIMIT_NOFILE,
+		"memlock":    8,
+		"as":         syscall.RLIMIT_AS,
+		"locks":      10,
+		"sigpending": 11,
+		"msgqueue":   12,
+		"nice":       13,
+		"rtprio":     14,
+		"rttime":     15,
+	}
+
+	ioClasses = map[string]int{
+		"none":        0,
+		"realtime":    1,
+		"best-effort": 2,
+		"idle":        3,
+	}
+)
+
+/*
+Credentials are the identity and the resource settings a system process runs with. Empty values
+keep the settings of core0 itself (root).
+*/
+type Credentials struct {
+	//User name or uid to run the process as
+	User string `json:"user,omitempty"`
+	//Group name or gid to run the process as, defaults to the primary group of User
+	Group string `json:"group,omitempty"`
+	//Groups supplementary group names or gids
+	Groups []string `json:"groups,omitempty"`
+	//Umask in octal (ex: "022")
+	Umask string `json:"umask,omitempty"`
+	//Nice scheduling priority (-20 to 19)
+	Nice int `json:"nice,omitempty"`
+	//IONiceClass io scheduling class (none, realtime, best-effort or idle) and IONiceLevel its priority (0 to 7)
+	IONiceClass string `json:"ionice_class,omitempty"`
+	IONiceLevel int    `json:"ionice_level,omitempty"`
+	//Rlimits resource limits by name (nofile, nproc, core, etc...), the value is used as soft and hard limit.
+	Rlimits map[string]uint64 `json:"rlimits,omitempty"`
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return user.LookupId(name)
+	}
+
+	return user.Lookup(name)
+}
+
+func lookupGroup(name string) (uint32, error) {
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+
+	group, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	gid, err := strconv.ParseUint(group.Gid, 10, 32)
+	return uint32(gid), err
+}
+
+//credential builds the process credential, nil is returned if no user or groups are set.
+func (c *Credentials) credential() (*syscall.Credential, error) {
+	if c.User == "" && c.Group == "" && len(c.Groups) == 0 {
+		return nil, nil
+	}
+
+	cred := &syscall.Credential{
+		//drop the supplementary groups of core0 unless explicitly set.
+		Groups: []uint32{},
+	}
+
+	if c.User != "" {
+		u, err := lookupUser(c.User)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user '%s': %s", c.User, err)
+		}
+
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		cred.Uid = uint32(uid)
+		cred.Gid = uint32(gid)
+	}
+
+	if c.Group != "" {
+		gid, err := lookupGroup(c.Group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group '%s': %s", c.Group, err)
+		}
+		cred.Gid = gid
+	}
+
+	for _, name := range c.Groups {
+		gid, err := lookupGroup(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group '%s': %s", name, err)
+		}
+		cred.Groups = append(cred.Groups, gid)
+	}
+
+	return cred, nil
+}
+
+//umask parses the umask, -1 is returned if not set
+func (c *Credentials) umask() (int, error) {
+	if c.Umask == "" {
+		return -1, nil
+	}
+
+	mask, err := strconv.ParseUint(c.Umask, 8, 32)
+	if err != nil || mask > 0777 {
+		return 0, fmt.Errorf("invalid umask '%s'", c.Umask)
+	}
+
+	return int(mask), nil
+}
+
+//Validate makes sure the user, groups and limits are valid before the process is started
+func (c *Credentials) Validate() error {
+	if _, err := c.credential(); err != nil {
+		return err
+	}
+
+	if _, err := c.umask(); err != nil {
+		return err
+	}
+
+	if c.Nice < -20 || c.Nice > 19 {
+		return fmt.Errorf("invalid nice value '%d'", c.Nice)
+	}
+
+	if c.IONiceClass != "" {
+		if _, ok := ioClasses[strings.ToLower(c.IONiceClass)]; !ok {
+			return fmt.Errorf("invalid io class '%s'", c.IONiceClass)
+		}
+	}
+
+	if c.IONiceLevel < 0 || c.IONiceLevel > 7 {
+		return fmt.Errorf("invalid io priority '%d'", c.IONiceLevel)
+	}
+
+	for name := range c.Rlimits {
+		if _, ok := rlimits[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown rlimit '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+//spawnSpec builds the spec the process is spawned with, so the settings apply before the command is executed
+func (c *Credentials) spawnSpec() (*spawnSpec, error) {
+	credential, err := c.credential()
+	if err != nil {
+		return nil, err
+	}
+
+	umask, err := c.umask()
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &spawnSpec{
+		Credential: credential,
+		Umask:      umask,
+		Nice:       c.Nice,
+	}
+
+	if c.IONiceClass != "" {
+		spec.IOPriority = ioClasses[strings.ToLower(c.IONiceClass)]<<ioprioClassShift | c.IONiceLevel
+	}
+
+	if len(c.Rlimits) > 0 {
+		spec.Rlimits = make(map[int]uint64)
+		for name, value := range c.Rlimits {
+			spec.Rlimits[rlimits[strings.ToLower(name)]] = value
+		}
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,62 @@
+package process
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestCredentialsApplyBeforeExec(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	umask := syscall.Umask(022)
+	syscall.Umask(umask)
+
+	cmd := &core.Command{
+		ID:      "credentials",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name: "sh",
+			//all the values are read by forked children
+			Args: []string{"-c", "id -u; ulimit -n; umask; cut -d' ' -f19 /proc/self/stat; ionice"},
+			Credentials: Credentials{
+				User:        "nobody",
+				Umask:       "027",
+				Nice:        -5,
+				IONiceClass: "best-effort",
+				IONiceLevel: 6,
+				Rlimits:     map[string]uint64{"nofile": 512},
+			},
+		}),
+	}
+
+	lines, exit := runSystem(t, cmd)
+	assert.Equal(t, stream.MessageExitSuccess, exit)
+	assert.Equal(t, []string{"65534", "512", "0027", "-5", "best-effort: prio 6"}, lines)
+
+	//the umask of core0 itself is untouched
+	current := syscall.Umask(umask)
+	assert.Equal(t, umask, current)
+}
+
+func TestCredentialsSpawnError(t *testing.T) {
+	cmd := &core.Command{
+		ID:      "credentials-error",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name: "true",
+			//raising the hard limit over the kernel max is refused even to root
+			Credentials: Credentials{
+				Rlimits: map[string]uint64{"nofile": 1 << 40},
+			},
+		}),
+	}
+
+	_, err := NewSystemProcess(testTable{}, cmd).Run()
+	assert.NotNil(t, err)
+}
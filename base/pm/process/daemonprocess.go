@@ -0,0 +1,198 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	//DaemonDialTimeout how long a call waits for the daemon socket to accept connections (the daemon may be
+	//restarting)
+	DaemonDialTimeout = 10 * time.Second
+
+	daemonDialRetry = 200 * time.Millisecond
+)
+
+//DaemonRequest is sent to the extension daemon for each call, as a single json line
+type DaemonRequest struct {
+	ID        string           `json:"id"`
+	Command   string           `json:"command"`
+	Arguments *json.RawMessage `json:"arguments"`
+	Tags      string           `json:"tags"`
+}
+
+//DaemonResult is the final result of a call
+type DaemonResult struct {
+	State string `json:"state"`
+	//Level result level (20 json by default), Data is only reported if set
+	Level int    `json:"level"`
+	Data  string `json:"data"`
+}
+
+/*
+DaemonResponse is a json line sent back by the daemon. Lines with no result are log messages that are passed to
+the message handlers of the job, the line with a result ends the call.
+*/
+type DaemonResponse struct {
+	Level   int           `json:"level"`
+	Message string        `json:"message"`
+	Result  *DaemonResult `json:"result,omitempty"`
+}
+
+type daemonProcess struct {
+	cmd    *core.Command
+	socket string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+//NewDaemonProcessFactory creates a factory for an extension daemon, each call is sent to the daemon over the
+//given unix socket.
+func NewDaemonProcessFactory(socket string) ProcessFactory {
+	return func(table PIDTable, cmd *core.Command) Process {
+		ctx, cancel := context.WithCancel(context.Background())
+		return &daemonProcess{
+			cmd:    cmd,
+			socket: socket,
+			ctx:    ctx,
+			cancel: cancel,
+		}
+	}
+}
+
+func (process *daemonProcess) Command() *core.Command {
+	return process.cmd
+}
+
+func (process *daemonProcess) dial() (net.Conn, error) {
+	var dialer net.Dialer
+	deadline := time.Now().Add(DaemonDialTimeout)
+	for {
+		conn, err := dialer.DialContext(process.ctx, "unix", process.socket)
+		if err == nil || time.Now().After(deadline) {
+			return conn, err
+		}
+
+		select {
+		case <-time.After(daemonDialRetry):
+		case <-process.ctx.Done():
+			return nil, process.ctx.Err()
+		}
+	}
+}
+
+func (process *daemonProcess) Run() (<-chan *stream.Message, error) {
+	conn, err := process.dial()
+	if err != nil {
+		process.cancel()
+		return nil, fmt.Errorf("extension daemon is not available: %s", err)
+	}
+
+	request := DaemonRequest{
+		ID:        process.cmd.ID,
+		Command:   process.cmd.Command,
+		Arguments: process.cmd.Arguments,
+		Tags:      process.cmd.Tags,
+	}
+
+	if err := json.NewEncoder(conn).Encode(&request); err != nil {
+		conn.Close()
+		process.cancel()
+		return nil, err
+	}
+
+	//closing the connection is the only way to interrupt the reader.
+	go func() {
+		<-process.ctx.Done()
+		conn.Close()
+	}()
+
+	channel := make(chan *stream.Message)
+	go process.read(conn, channel)
+
+	return channel, nil
+}
+
+func (process *daemonProcess) read(conn net.Conn, channel chan *stream.Message) {
+	defer close(channel)
+	defer process.cancel()
+
+	decoder := json.NewDecoder(conn)
+	for {
+		var response DaemonResponse
+		if err := decoder.Decode(&response); err != nil {
+			if process.ctx.Err() == nil {
+				channel <- &stream.Message{
+					Level:   stream.LevelCritical,
+					Message: fmt.Sprintf("lost connection to extension daemon: %s", err),
+				}
+			}
+
+			channel <- stream.MessageExitError
+			return
+		}
+
+		if response.Result == nil {
+			if response.Level == 0 {
+				response.Level = stream.LevelStdout
+			}
+
+			channel <- &stream.Message{
+				Level:   response.Level,
+				Message: response.Message,
+			}
+			continue
+		}
+
+		result := response.Result
+		if result.Data != "" {
+			if result.Level == 0 {
+				result.Level = stream.LevelResultJSON
+			}
+
+			channel <- &stream.Message{
+				Level:   result.Level,
+				Message: result.Data,
+			}
+		}
+
+		if result.State == "" {
+			result.State = core.StateSuccess
+		}
+
+		channel <- &stream.Message{
+			Level:   stream.LevelExitState,
+			Message: result.State,
+		}
+		return
+	}
+}
+
+/*
+Kill aborts the call by closing the connection, the daemon itself keeps running
+*/
+func (process *daemonProcess) Kill() {
+	process.cancel()
+}
+
+/*
+Signal signals the daemon call (not supported)
+*/
+func (process *daemonProcess) Signal(sig syscall.Signal) error {
+	return fmt.Errorf("can't signal an extension daemon call")
+}
+
+/*
+GetStats gets the consumption of the call, which is accounted to the daemon job (not implemented)
+*/
+func (process *daemonProcess) GetStats() *ProcessStats {
+	return &ProcessStats{
+		Cmd: process.cmd,
+	}
+}
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestDaemonProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer os.RemoveAll(dir)
+
+	socket := path.Join(dir, "ext.sock")
+	listener, err := net.Listen("unix", socket)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	defer listener.Close()
+
+	requests := make(chan DaemonRequest, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var request DaemonRequest
+		json.NewDecoder(conn).Decode(&request)
+		requests <- request
+
+		encoder := json.NewEncoder(conn)
+		encoder.Encode(DaemonResponse{Level: stream.LevelStderr, Message: "working"})
+		encoder.Encode(DaemonResponse{Result: &DaemonResult{Data: `"done"`}})
+	}()
+
+	cmd := &core.Command{
+		ID:        "call",
+		Command:   "ext",
+		Arguments: core.MustArguments(map[string]string{"name": "value"}),
+	}
+
+	channel, err := NewDaemonProcessFactory(socket)(nil, cmd).Run()
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	var messages []stream.Message
+	for msg := range channel {
+		messages = append(messages, *msg)
+	}
+
+	request := <-requests
+	assert.Equal(t, "call", request.ID)
+	assert.Equal(t, "ext", request.Command)
+	assert.JSONEq(t, `{"name": "value"}`, string(*request.Arguments))
+
+	assert.Equal(t, []stream.Message{
+		{Level: stream.LevelStderr, Message: "working"},
+		{Level: stream.LevelResultJSON, Message: `"done"`},
+		{Level: stream.LevelExitState, Message: core.StateSuccess},
+	}, messages)
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/stream"
 	"github.com/g8os/core0/base/utils"
+	"io"
+	"syscall"
 )
 
 type extensionProcess struct {
@@ -12,12 +14,14 @@ type extensionProcess struct {
 	cmd    *core.Command
 }
 
-func NewExtensionProcessFactory(exe string, dir string, args []string, env map[string]string) ProcessFactory {
+//NewExtensionProcessFactory creates a factory for an extension, the extension process runs with the given credentials
+func NewExtensionProcessFactory(exe string, dir string, args []string, env map[string]string, creds Credentials) ProcessFactory {
 	constructor := func(table PIDTable, cmd *core.Command) Process {
 		sysargs := SystemCommandArguments{
-			Name: exe,
-			Dir:  dir,
-			Env:  env,
+			Name:        exe,
+			Dir:         dir,
+			Env:         env,
+			Credentials: creds,
 		}
 
 		var input map[string]interface{}
@@ -39,15 +43,23 @@ func NewExtensionProcessFactory(exe string, dir string, args []string, env map[s
 			delete(input, "stdin")
 		}
 
+		if keep, ok := input["keep_stdin"].(bool); ok {
+			sysargs.KeepStdIn = keep
+			delete(input, "keep_stdin")
+		}
+
 		for _, arg := range args {
 			sysargs.Args = append(sysargs.Args, utils.Format(arg, input))
 		}
 
 		extcmd := &core.Command{
-			ID:        cmd.ID,
-			Command:   CommandSystem,
-			Arguments: core.MustArguments(sysargs),
-			Tags:      cmd.Tags,
+			ID:          cmd.ID,
+			Command:     CommandSystem,
+			Arguments:   core.MustArguments(sysargs),
+			Limits:      cmd.Limits,
+			StopSignal:  cmd.StopSignal,
+			StopTimeout: cmd.StopTimeout,
+			Tags:        cmd.Tags,
 		}
 
 		return &extensionProcess{
@@ -71,6 +83,26 @@ func (process *extensionProcess) Kill() {
 	process.system.Kill()
 }
 
+func (process *extensionProcess) Stdin() io.WriteCloser {
+	if stdin, ok := process.system.(StdinProcess); ok {
+		return stdin.Stdin()
+	}
+
+	return nil
+}
+
+func (process *extensionProcess) ExitStatus() *core.ExitStatus {
+	if exit, ok := process.system.(ExitProcess); ok {
+		return exit.ExitStatus()
+	}
+
+	return nil
+}
+
+func (process *extensionProcess) Signal(sig syscall.Signal) error {
+	return process.system.Signal(sig)
+}
+
 func (process *extensionProcess) GetStats() *ProcessStats {
 	return process.system.GetStats()
 }
@@ -4,7 +4,9 @@ import (
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/stream"
 	"github.com/op/go-logging"
+	"io"
 	"syscall"
+	"time"
 )
 
 const (
@@ -21,7 +23,34 @@ type PIDTable interface {
 	//Register atomic registration of PID. MUST grantee that that no wait4 will happen
 	//on any of the child process until the register operation is done.
 	Register(g GetPID) error
-	WaitPID(pid int) *syscall.WaitStatus
+	WaitPID(pid int) *ProcessState
+}
+
+//ProcessState is the state of an exited process as collected by wait4
+type ProcessState struct {
+	Status syscall.WaitStatus
+	Rusage syscall.Rusage
+}
+
+//ExitStatus converts the wait status and resource usage to a core exit status
+func (s *ProcessState) ExitStatus() *core.ExitStatus {
+	status := &core.ExitStatus{
+		Code:       s.Status.ExitStatus(),
+		UserTime:   time.Duration(s.Rusage.Utime.Nano()).Seconds(),
+		SystemTime: time.Duration(s.Rusage.Stime.Nano()).Seconds(),
+		//linux reports the max rss in kilobytes
+		MaxRSS:   int64(s.Rusage.Maxrss) * 1024,
+		InBlock:  int64(s.Rusage.Inblock),
+		OutBlock: int64(s.Rusage.Oublock),
+	}
+
+	if s.Status.Signaled() {
+		status.Signal = int(s.Status.Signal())
+		status.Code = 128 + status.Signal
+		status.CoreDump = s.Status.CoreDump()
+	}
+
+	return status
 }
 
 //ProcessStats holds process cpu and memory usage
@@ -39,7 +68,37 @@ type Process interface {
 	Command() *core.Command
 	Run() (<-chan *stream.Message, error)
 	Kill()
+	Signal(sig syscall.Signal) error
 	GetStats() *ProcessStats
 }
 
+//ExitProcess is implemented by processes that can report their exit status
+type ExitProcess interface {
+	//ExitStatus gets the exit status of the process, nil if the process didn't exit yet
+	ExitStatus() *core.ExitStatus
+}
+
+//StdinProcess is implemented by processes that can keep their stdin open while running
+type StdinProcess interface {
+	//Stdin gets the process stdin, nil if the stdin was not kept open
+	Stdin() io.WriteCloser
+}
+
 type ProcessFactory func(PIDTable, *core.Command) Process
+
+//consume starts consuming the process outputs, the readers are consumed in order as stdout (level 1)
+//and stderr (level 2), nil readers are skipped.
+func consume(handler stream.MessageHandler, readers ...io.Reader) []stream.Consumer {
+	var consumers []stream.Consumer
+	for i, reader := range readers {
+		if reader == nil {
+			continue
+		}
+
+		consumer := stream.NewConsumer(reader, i+1)
+		consumer.Consume(handler)
+		consumers = append(consumers, consumer)
+	}
+
+	return consumers
+}
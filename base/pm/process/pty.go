@@ -0,0 +1,119 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	//DefaultTTYRows default number of rows of the process pseudo terminal
+	DefaultTTYRows = 24
+	//DefaultTTYCols default number of columns of the process pseudo terminal
+	DefaultTTYCols = 80
+
+	eot = 0x04
+)
+
+//TTYProcess is implemented by processes that can run attached to a pseudo terminal
+type TTYProcess interface {
+	//Resize changes the window size of the process terminal
+	Resize(rows, cols uint16) error
+}
+
+type winsize struct {
+	rows   uint16
+	cols   uint16
+	xpixel uint16
+	ypixel uint16
+}
+
+func ioctl(fd uintptr, cmd uintptr, ptr uintptr) error {
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, ptr); e != 0 {
+		return e
+	}
+
+	return nil
+}
+
+//openPty opens a new pseudo terminal pair with the given window size
+func openPty(rows, cols uint16) (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			master.Close()
+		}
+	}()
+
+	var unlock int32
+	if err = ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		return nil, nil, fmt.Errorf("unlock pty: %s", err)
+	}
+
+	var n uint32
+	if err = ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		return nil, nil, fmt.Errorf("get pty number: %s", err)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = setWindowSize(master, rows, cols); err != nil {
+		slave.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
+
+//setWindowSize sets the terminal window size, zero values falls back to the defaults.
+func setWindowSize(tty *os.File, rows, cols uint16) error {
+	if rows == 0 {
+		rows = DefaultTTYRows
+	}
+
+	if cols == 0 {
+		cols = DefaultTTYCols
+	}
+
+	ws := winsize{rows: rows, cols: cols}
+	return ioctl(tty.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+}
+
+//ptyReader reads the pty master, the master returns EIO once the slave side is closed (process exited) so
+//we report it as EOF
+type ptyReader struct {
+	master *os.File
+}
+
+func (r *ptyReader) Read(p []byte) (int, error) {
+	n, err := r.master.Read(p)
+	if perr, ok := err.(*os.PathError); ok && perr.Err == syscall.EIO {
+		return n, io.EOF
+	}
+
+	return n, err
+}
+
+//ptyStdin writes to the process terminal, closing it sends an EOT (ctrl-d) instead of closing the
+//terminal itself
+type ptyStdin struct {
+	master *os.File
+}
+
+func (w *ptyStdin) Write(p []byte) (int, error) {
+	return w.master.Write(p)
+}
+
+func (w *ptyStdin) Close() error {
+	_, err := w.master.Write([]byte{eot})
+	return err
+}
@@ -0,0 +1,70 @@
+package process
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPtyResize(t *testing.T) {
+	cmd := &core.Command{
+		ID:      "pty",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name: "sh",
+			Args: []string{"-c", "tty; stty size; read line; stty size"},
+			TTY:  true,
+			Rows: 30,
+			Cols: 100,
+		}),
+	}
+
+	ps := NewSystemProcess(testTable{}, cmd)
+	channel, err := ps.Run()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	var lines []string
+	next := func() *stream.Message {
+		select {
+		case msg, ok := <-channel:
+			if !ok {
+				t.Fatalf("process exited, got: %v", lines)
+			}
+			if msg.Level == stream.LevelStdout {
+				lines = append(lines, strings.TrimSpace(msg.Message))
+			}
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no output, got: %v", lines)
+		}
+		return nil
+	}
+
+	//the process is waiting for input, the terminal wasn't closed
+	for next().Message != "30 100" {
+	}
+
+	assert.Nil(t, ps.(TTYProcess).Resize(40, 120))
+	stdin := ps.(StdinProcess).Stdin()
+	if !assert.NotNil(t, stdin) {
+		t.FailNow()
+	}
+	stdin.Write([]byte("go\n"))
+
+	exit := next()
+	for exit.Level != stream.LevelExitState {
+		exit = next()
+	}
+
+	assert.Equal(t, stream.MessageExitSuccess, exit)
+	if assert.True(t, len(lines) >= 3, "%v", lines) {
+		assert.True(t, strings.HasPrefix(lines[0], "/dev/pts/"), lines[0])
+	}
+	assert.Contains(t, lines, "30 100")
+	assert.Contains(t, lines, "40 120")
+}
@@ -0,0 +1,127 @@
+package process
+
+import (
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	//DefaultStopSignal signal sent to the process on kill, if the command doesn't specify one.
+	DefaultStopSignal = syscall.SIGTERM
+	//DefaultStopTimeout grace period (in seconds) before the process group is SIGKILLed.
+	DefaultStopTimeout = 5
+)
+
+var (
+	signals = map[string]syscall.Signal{
+		"HUP":    syscall.SIGHUP,
+		"INT":    syscall.SIGINT,
+		"QUIT":   syscall.SIGQUIT,
+		"ABRT":   syscall.SIGABRT,
+		"KILL":   syscall.SIGKILL,
+		"USR1":   syscall.SIGUSR1,
+		"USR2":   syscall.SIGUSR2,
+		"PIPE":   syscall.SIGPIPE,
+		"ALRM":   syscall.SIGALRM,
+		"TERM":   syscall.SIGTERM,
+		"CONT":   syscall.SIGCONT,
+		"STOP":   syscall.SIGSTOP,
+		"TSTP":   syscall.SIGTSTP,
+		"TTIN":   syscall.SIGTTIN,
+		"TTOU":   syscall.SIGTTOU,
+		"WINCH":  syscall.SIGWINCH,
+		"PWR":    syscall.SIGPWR,
+		"SYS":    syscall.SIGSYS,
+		"XCPU":   syscall.SIGXCPU,
+		"XFSZ":   syscall.SIGXFSZ,
+		"VTALRM": syscall.SIGVTALRM,
+		"PROF":   syscall.SIGPROF,
+		"IO":     syscall.SIGIO,
+		"URG":    syscall.SIGURG,
+	}
+)
+
+//ParseSignal parses a signal of the formats 'SIGTERM', 'TERM' or '15'
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		if n == 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number '%d'", n)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	if sig, ok := signals[strings.TrimPrefix(s, "SIG")]; ok {
+		return sig, nil
+	}
+
+	return 0, fmt.Errorf("unknown signal '%s'", s)
+}
+
+//stopSettings gets the stop signal and grace period of the command
+func stopSettings(cmd *core.Command) (syscall.Signal, time.Duration) {
+	sig := DefaultStopSignal
+	if cmd.StopSignal != "" {
+		if s, err := ParseSignal(cmd.StopSignal); err == nil {
+			sig = s
+		} else {
+			log.Errorf("Invalid stop signal for command %s: %s", cmd, err)
+		}
+	}
+
+	timeout := DefaultStopTimeout
+	if cmd.StopTimeout > 0 {
+		timeout = cmd.StopTimeout
+	}
+
+	return sig, time.Duration(timeout) * time.Second
+}
+
+/*
+stop sends the command stop signal to the process group of pid, and to the escaped processes (descendants that
+left the group), then waits for exited to get closed. If the process didn't exit within the command grace period
+the whole process group and the escaped processes are SIGKILLed.
+
+stop doesn't block, the caller is expected to wait on the process exit as usual.
+*/
+func stop(cmd *core.Command, pid int, exited <-chan struct{}, escaped ...int) {
+	if pid <= 0 {
+		return
+	}
+
+	sig, grace := stopSettings(cmd)
+	log.Debugf("Stopping %s with signal %d", cmd, sig)
+	if err := syscall.Kill(-pid, sig); err != nil {
+		log.Errorf("Failed to signal process group of %s: %s", cmd, err)
+	}
+
+	for _, p := range escaped {
+		log.Infof("Stopping process %d of %s", p, cmd)
+		if err := syscall.Kill(p, sig); err != nil {
+			log.Errorf("Failed to signal process %d of %s: %s", p, cmd, err)
+		}
+	}
+
+	if sig == syscall.SIGKILL {
+		return
+	}
+
+	go func() {
+		select {
+		case <-exited:
+		case <-time.After(grace):
+			log.Warningf("Process %s didn't exit in %s, killing", cmd, grace)
+			if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil {
+				log.Errorf("Failed to kill process group of %s: %s", cmd, err)
+			}
+
+			for _, p := range escaped {
+				syscall.Kill(p, syscall.SIGKILL)
+			}
+		}
+	}()
+}
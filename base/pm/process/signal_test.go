@@ -0,0 +1,37 @@
+package process
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, s := range []string{"SIGHUP", "HUP", "hup", "1"} {
+		sig, err := ParseSignal(s)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+
+		assert.Equal(t, syscall.SIGHUP, sig)
+	}
+}
+
+func TestParseSignalInvalid(t *testing.T) {
+	for _, s := range []string{"", "SIGNOPE", "0", "100"} {
+		_, err := ParseSignal(s)
+		assert.Error(t, err)
+	}
+}
+
+func TestStopSettings(t *testing.T) {
+	sig, grace := stopSettings(&core.Command{})
+	assert.Equal(t, DefaultStopSignal, sig)
+	assert.Equal(t, DefaultStopTimeout*time.Second, grace)
+
+	sig, grace = stopSettings(&core.Command{StopSignal: "INT", StopTimeout: 30})
+	assert.Equal(t, syscall.SIGINT, sig)
+	assert.Equal(t, 30*time.Second, grace)
+}
@@ -0,0 +1,168 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+const (
+	//spawnHelper is the argv[0] the process manager binary is re-executed with to spawn a command
+	spawnHelper = "core-spawn"
+	//spawnStatusFd is the child end of the pipe the spawn helper reports its errors on
+	spawnStatusFd = 3
+	spawnExitCode = 127
+)
+
+/*
+spawnSpec is what the spawn helper applies to itself before it executes the command. Go can't run code in the
+child between fork and exec, so the binary is executed again as a helper that sets up the process and then
+executes the command in place, which keeps the pid. Anything the command forks is covered from the start.
+*/
+type spawnSpec struct {
+	//Path of the executable, Args[0] is kept as is
+	Path string
+	Args []string
+	//CGroups are the cgroup.procs files of the groups to join
+	CGroups []string `json:",omitempty"`
+	//Rlimits by resource, the value is used as soft and hard limit
+	Rlimits map[int]uint64 `json:",omitempty"`
+	Nice    int            `json:",omitempty"`
+	//IOPriority as given to ioprio_set (class and level), 0 keeps the default
+	IOPriority int `json:",omitempty"`
+	//Umask -1 keeps the umask of core0
+	Umask int
+	//Credential to switch to, right before the exec
+	Credential *syscall.Credential `json:",omitempty"`
+}
+
+//needed tells if the command can be started without the helper
+func (s *spawnSpec) needed() bool {
+	return len(s.CGroups) > 0 || len(s.Rlimits) > 0 || s.Nice != 0 || s.IOPriority != 0 || s.Umask >= 0
+}
+
+func init() {
+	if len(os.Args) < 2 || os.Args[0] != spawnHelper {
+		return
+	}
+
+	//priorities are per thread, the thread that sets them must be the one that executes the command.
+	runtime.LockOSThread()
+	syscall.CloseOnExec(spawnStatusFd)
+
+	err := spawnExec(os.Args[1])
+	status := os.NewFile(spawnStatusFd, "status")
+	status.Write([]byte(err.Error()))
+	os.Exit(spawnExitCode)
+}
+
+//spawnExec runs in the helper, it only returns on failure
+func spawnExec(data string) error {
+	var spec spawnSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return err
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+	for _, procs := range spec.CGroups {
+		if err := ioutil.WriteFile(procs, []byte(pid), 0644); err != nil {
+			return fmt.Errorf("failed to apply limits: %s", err)
+		}
+	}
+
+	for resource, value := range spec.Rlimits {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %s", resource, err)
+		}
+	}
+
+	if spec.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, spec.Nice); err != nil {
+			return fmt.Errorf("failed to set nice: %s", err)
+		}
+	}
+
+	if spec.IOPriority != 0 {
+		if _, _, e := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(spec.IOPriority)); e != 0 {
+			return fmt.Errorf("failed to set io priority: %s", e)
+		}
+	}
+
+	if spec.Umask >= 0 {
+		syscall.Umask(spec.Umask)
+	}
+
+	//the credential must come last, everything else needs the privileges of core0
+	if cred := spec.Credential; cred != nil {
+		groups := make([]int, 0, len(cred.Groups))
+		for _, gid := range cred.Groups {
+			groups = append(groups, int(gid))
+		}
+
+		if err := syscall.Setgroups(groups); err != nil {
+			return fmt.Errorf("failed to set groups: %s", err)
+		}
+		if err := syscall.Setgid(int(cred.Gid)); err != nil {
+			return fmt.Errorf("failed to set group: %s", err)
+		}
+		if err := syscall.Setuid(int(cred.Uid)); err != nil {
+			return fmt.Errorf("failed to set user: %s", err)
+		}
+	}
+
+	return syscall.Exec(spec.Path, spec.Args, os.Environ())
+}
+
+/*
+spawn starts the command with the given spec. If the spec needs the helper, the command is started through it and
+spawn only returns once the command itself was executed (or failed to be).
+*/
+func spawn(cmd *exec.Cmd, spec *spawnSpec) error {
+	if !spec.needed() {
+		cmd.SysProcAttr.Credential = spec.Credential
+		return cmd.Start()
+	}
+
+	if cmd.Err != nil {
+		//executable lookup failed
+		return cmd.Err
+	}
+
+	spec.Path = cmd.Path
+	spec.Args = cmd.Args
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{spawnHelper, string(data)}
+	cmd.ExtraFiles = []*os.File{w}
+	//the helper needs the privileges of core0, it drops them itself
+	cmd.SysProcAttr.Credential = nil
+
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	//the status pipe is closed on exec, anything written to it is an error of the helper
+	status, _ := ioutil.ReadAll(r)
+	if len(status) > 0 {
+		return fmt.Errorf("%s", status)
+	}
+
+	return nil
+}
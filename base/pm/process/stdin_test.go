@@ -0,0 +1,61 @@
+package process
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStdinCloseInterruptsWrite(t *testing.T) {
+	cmd := &core.Command{
+		ID:      "stdin",
+		Command: CommandSystem,
+		Arguments: core.MustArguments(SystemCommandArguments{
+			Name:      "sleep",
+			Args:      []string{"5"},
+			KeepStdIn: true,
+		}),
+	}
+
+	ps := NewSystemProcess(testTable{}, cmd)
+	channel, err := ps.Run()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer func() {
+		ps.Kill()
+		for range channel {
+		}
+	}()
+
+	stdin := ps.(StdinProcess).Stdin()
+
+	//sleep never reads, the write blocks once the pipe buffer is full
+	written := make(chan error, 1)
+	go func() {
+		_, err := stdin.Write(make([]byte, 1024*1024))
+		written <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stdin.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("close is blocked by the write")
+	}
+
+	select {
+	case err := <-written:
+		assert.NotNil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("write was not interrupted by the close")
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/stream"
 	psutils "github.com/shirou/gopsutil/process"
+	"io"
+	"os"
 	"os/exec"
+	"sync"
+	"syscall"
 )
 
 type SystemCommandArguments struct {
@@ -15,23 +19,81 @@ type SystemCommandArguments struct {
 	Args  []string          `json:"args"`
 	Env   map[string]string `json:"env"`
 	StdIn []byte            `json:"stdin"`
+	//KeepStdIn keeps the process stdin open after writing StdIn, so more data can be written to
+	//the process while running
+	KeepStdIn bool `json:"keep_stdin,omitempty"`
+	//TTY runs the process attached to a pseudo terminal of the given size (Rows x Cols), the terminal input
+	//is always kept open
+	TTY  bool   `json:"tty,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	//Credentials of the process, by default the process runs as root
+	Credentials
+}
+
+/*
+stdinPipe guards the process stdin against concurrent writes and double close. Close doesn't wait for the writes, a
+write blocked on a process that doesn't read its input is interrupted by the close.
+*/
+type stdinPipe struct {
+	pipe   io.WriteCloser
+	closed bool
+	//w serializes the writes, m protects closed
+	w sync.Mutex
+	m sync.Mutex
+}
+
+func (p *stdinPipe) Write(data []byte) (int, error) {
+	p.w.Lock()
+	defer p.w.Unlock()
+
+	p.m.Lock()
+	closed := p.closed
+	p.m.Unlock()
+
+	if closed {
+		return 0, fmt.Errorf("stdin is closed")
+	}
+
+	return p.pipe.Write(data)
+}
+
+func (p *stdinPipe) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		return fmt.Errorf("stdin is already closed")
+	}
+
+	p.closed = true
+	return p.pipe.Close()
 }
 
 type systemProcessImpl struct {
-	cmd      *core.Command
-	args     SystemCommandArguments
-	pid      int
-	process  *psutils.Process
-	children []*psutils.Process
+	cmd     *core.Command
+	args    SystemCommandArguments
+	pid     int
+	process *psutils.Process
+	cgroup  *cgroup
+	exited  chan struct{}
+	stdin   *stdinPipe
+	tty     *os.File
+	exit    *core.ExitStatus
+
+	//monitored pids explicitly reported by the process (LevelInternalMonitorPid), they and their
+	//descendants are accounted for even if they left the process group.
+	monitored    []int
+	monitoredMux sync.Mutex
+	tracker      tracker
 
 	table PIDTable
 }
 
 func NewSystemProcess(table PIDTable, cmd *core.Command) Process {
 	process := &systemProcessImpl{
-		cmd:      cmd,
-		children: make([]*psutils.Process, 0),
-		table:    table,
+		cmd:    cmd,
+		exited: make(chan struct{}),
+		table:  table,
 	}
 
 	json.Unmarshal(*cmd.Arguments, &process.args)
@@ -44,11 +106,62 @@ func (process *systemProcessImpl) Command() *core.Command {
 
 func (process *systemProcessImpl) Kill() {
 	//should force system process to exit.
-	if process.process != nil {
-		process.process.Terminate()
+	if process.pid <= 0 {
+		return
+	}
+
+	//descendants that left the process group (or session) are not reached by signaling the group
+	stop(process.cmd, process.pid, process.exited, escaped(process.pid, process.descendants())...)
+}
+
+//descendants gets the pids of all the processes of the job (except the main process), processes in the job
+//cgroup are always included even if they were re-parented.
+func (process *systemProcessImpl) descendants() []int {
+	process.monitoredMux.Lock()
+	extra := append([]int{}, process.monitored...)
+	process.monitoredMux.Unlock()
+
+	if process.cgroup != nil {
+		extra = append(extra, process.cgroup.Pids()...)
+	}
+
+	return descendants(process.pid, extra...)
+}
+
+//Stdin gets the process stdin if it was kept open
+func (process *systemProcessImpl) Stdin() io.WriteCloser {
+	if process.stdin == nil {
+		return nil
 	}
 
-	process.killChildren()
+	return process.stdin
+}
+
+//Resize changes the window size of the process terminal
+func (process *systemProcessImpl) Resize(rows, cols uint16) error {
+	if process.tty == nil {
+		return fmt.Errorf("process has no tty")
+	}
+
+	return setWindowSize(process.tty, rows, cols)
+}
+
+//ExitStatus gets the exit status of the process
+func (process *systemProcessImpl) ExitStatus() *core.ExitStatus {
+	select {
+	case <-process.exited:
+		return process.exit
+	default:
+		return nil
+	}
+}
+
+func (process *systemProcessImpl) Signal(sig syscall.Signal) error {
+	if process.pid <= 0 {
+		return fmt.Errorf("process is not running")
+	}
+
+	return syscall.Kill(process.pid, sig)
 }
 
 //GetStats gets stats of an external process
@@ -81,29 +194,14 @@ func (process *systemProcessImpl) GetStats() *ProcessStats {
 
 	stats.Debug = fmt.Sprintf("%d", process.process.Pid)
 
-	for i := 0; i < len(process.children); i++ {
-		child := process.children[i]
-
-		childCPU, err := child.Percent(0)
-		if err != nil {
-			log.Errorf("%s", err)
-			//remove the dead process.
-			process.children = append(process.children[:i], process.children[i+1:]...)
-			continue
-		}
-
-		stats.CPU += childCPU
-		childMem, err := child.MemoryInfo()
-		if err == nil {
-			stats.Debug = fmt.Sprintf("%s %d", stats.Debug, child.Pid)
-			stats.RSS += childMem.RSS
-			stats.Swap += childMem.Swap
-			stats.VMS += childMem.VMS
-		} else {
-			log.Errorf("%s", err)
-		}
+	if process.cgroup != nil {
+		//the cgroup accounts for the process and all of its children.
+		process.cgroup.Stats(&stats)
+		return &stats
 	}
 
+	process.tracker.add(process.descendants(), &stats)
+
 	return &stats
 }
 
@@ -140,23 +238,15 @@ func (process *systemProcessImpl) processInternalMessage(msg *stream.Message) {
 			return
 		}
 		log.Infof("Tracking external process: %d", childPid)
-		child, err := psutils.NewProcess(int32(childPid))
-		if err != nil {
-			log.Errorf("%s", err)
-		}
-		process.children = append(process.children, child)
+		process.monitoredMux.Lock()
+		process.monitored = append(process.monitored, childPid)
+		process.monitoredMux.Unlock()
 	}
 }
 
-func (process *systemProcessImpl) killChildren() {
-	for _, child := range process.children {
-		//kill grand-child process.
-		log.Infof("Killing grandchild process '%d'", child.Pid)
-
-		err := child.Terminate()
-		if err != nil {
-			log.Errorf("Failed to kill child process: %s", err)
-		}
+func (process *systemProcessImpl) closeTTY() {
+	if process.tty != nil {
+		process.tty.Close()
 	}
 }
 
@@ -165,29 +255,78 @@ func (process *systemProcessImpl) Run() (<-chan *stream.Message, error) {
 		process.args.Args...)
 	cmd.Dir = process.args.Dir
 
-	for k, v := range process.args.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", k, v))
+	if err := process.args.Credentials.Validate(); err != nil {
+		return nil, err
 	}
 
-	stdout, err := cmd.StdoutPipe()
+	spec, err := process.args.Credentials.spawnSpec()
 	if err != nil {
 		return nil, err
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
+	//run in a separate process group so the process and all of its children can be stopped at once.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
 	}
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, err
+	for k, v := range process.args.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", k, v))
 	}
+
+	var stdout, stderr io.Reader
+	var stdin io.WriteCloser
+
+	if process.args.TTY {
+		master, slave, err := openPty(process.args.Rows, process.args.Cols)
+		if err != nil {
+			return nil, err
+		}
+
+		//the slave end is only needed by the child
+		defer slave.Close()
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		//a new session is needed to acquire the controlling terminal, the session
+		//leader is also a process group leader.
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Setsid:  true,
+			Setctty: true,
+		}
+
+		process.tty = master
+		stdout = &ptyReader{master}
+		stdin = &ptyStdin{master}
+	} else {
+		var err error
+		if stdout, err = cmd.StdoutPipe(); err != nil {
+			return nil, err
+		}
+
+		if stderr, err = cmd.StderrPipe(); err != nil {
+			return nil, err
+		}
+
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return nil, err
+		}
+	}
+
+	if process.cmd.Limits != nil {
+		cg, err := newCGroup(process.cmd.ID, process.cmd.Limits)
+		if err != nil {
+			process.closeTTY()
+			return nil, fmt.Errorf("failed to create cgroup: %s", err)
+		}
+		process.cgroup = cg
+		//the process joins the group before it executes the command
+		spec.CGroups = cg.procs()
+	}
+
 	log.Debugf("system: %s %s %s", cmd.Env, cmd.Path, cmd.Args)
 	//starttime := time.Duration(time.Now().UnixNano()) / time.Millisecond // start time in msec
 	err = process.table.Register(func() (int, error) {
-		err := cmd.Start()
-		if err != nil {
+		if err := spawn(cmd, spec); err != nil {
 			return 0, err
 		}
 
@@ -196,6 +335,10 @@ func (process *systemProcessImpl) Run() (<-chan *stream.Message, error) {
 
 	if err != nil {
 		log.Errorf("Failed to start process(%s): %s", process.cmd.ID, err)
+		if process.cgroup != nil {
+			process.cgroup.Remove()
+		}
+		process.closeTTY()
 		return nil, err
 	}
 
@@ -221,34 +364,48 @@ func (process *systemProcessImpl) Run() (<-chan *stream.Message, error) {
 	}
 
 	// start consuming outputs.
-	outConsumer := stream.NewConsumer(stdout, 1)
-	outConsumer.Consume(msgInterceptor)
-
-	errConsumer := stream.NewConsumer(stderr, 2)
-	errConsumer.Consume(msgInterceptor)
+	consumers := consume(msgInterceptor, stdout, stderr)
 
 	if len(process.args.StdIn) != 0 {
 		//write data to command stdin.
-		_, err = stdin.Write(process.args.StdIn)
-		if err != nil {
+		if _, err := stdin.Write(process.args.StdIn); err != nil {
 			log.Errorf("Failed to write to process stdin: %s", err)
 		}
 	}
 
-	stdin.Close()
+	if process.args.KeepStdIn || process.args.TTY {
+		//a terminal is interactive, closing it would send an EOT before anyone could type
+		process.stdin = &stdinPipe{pipe: stdin}
+	} else {
+		stdin.Close()
+	}
 
 	go func(channel chan *stream.Message) {
 		//make sure all outputs are closed before waiting for the process
 		//to exit.
 		defer close(channel)
 
-		<-outConsumer.Signal()
-		<-errConsumer.Signal()
+		for _, consumer := range consumers {
+			<-consumer.Signal()
+		}
+
 		state := process.table.WaitPID(process.pid)
+		process.exit = state.ExitStatus()
+		close(process.exited)
+
+		if process.stdin != nil {
+			//the process is gone, nothing can be written to it anymore.
+			process.stdin.Close()
+		}
+		process.closeTTY()
 
-		log.Infof("Process %s exited with state: %d", process.cmd, state.ExitStatus())
+		log.Infof("Process %s exited with state: %d", process.cmd, process.exit.Code)
+
+		if process.cgroup != nil {
+			process.cgroup.Remove()
+		}
 
-		if state.ExitStatus() == 0 {
+		if state.Status.ExitStatus() == 0 {
 			channel <- stream.MessageExitSuccess
 		} else {
 			channel <- stream.MessageExitError
@@ -0,0 +1,177 @@
+package process
+
+import (
+	"fmt"
+	psutils "github.com/shirou/gopsutil/process"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const procRoot = "/proc"
+
+type procStat struct {
+	pid     int
+	ppid    int
+	pgrp    int
+	session int
+}
+
+func readProcStat(pid int) (*procStat, error) {
+	data, err := ioutil.ReadFile(path.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	//the process name can contain spaces and parentheses, so fields are read after the last ')'
+	content := string(data)
+	idx := strings.LastIndex(content, ")")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid stat format for process %d", pid)
+	}
+
+	//state ppid pgrp session ...
+	fields := strings.Fields(content[idx+1:])
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid stat format for process %d", pid)
+	}
+
+	stat := &procStat{pid: pid}
+	for i, v := range []*int{&stat.ppid, &stat.pgrp, &stat.session} {
+		if *v, err = strconv.Atoi(fields[i+1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return stat, nil
+}
+
+func procStats() []*procStat {
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		log.Errorf("Failed to list processes: %s", err)
+		return nil
+	}
+
+	var stats []*procStat
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		//the process may have exited in the meantime
+		if stat, err := readProcStat(pid); err == nil {
+			stats = append(stats, stat)
+		}
+	}
+
+	return stats
+}
+
+/*
+descendants gets the pids of all the living processes that belong to the job started with pid. That's every
+process in its process group or session (the job process is always a group or session leader) plus all the
+processes that descend from pid, or from one of the extra pids. pid itself is not included.
+*/
+func descendants(pid int, extra ...int) []int {
+	stats := procStats()
+	children := make(map[int][]int)
+	found := make(map[int]bool)
+
+	for _, stat := range stats {
+		children[stat.ppid] = append(children[stat.ppid], stat.pid)
+		if stat.pgrp == pid || stat.session == pid {
+			found[stat.pid] = true
+		}
+	}
+
+	queue := []int{pid}
+	for _, p := range extra {
+		if _, err := readProcStat(p); err == nil {
+			found[p] = true
+			queue = append(queue, p)
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, child := range children[p] {
+			if !found[child] {
+				found[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	delete(found, pid)
+	pids := make([]int, 0, len(found))
+	for p := range found {
+		pids = append(pids, p)
+	}
+
+	return pids
+}
+
+//escaped filters the pids that are not part of the process group pgrp, so they can't be reached by signaling the group
+func escaped(pgrp int, pids []int) []int {
+	var out []int
+	for _, pid := range pids {
+		stat, err := readProcStat(pid)
+		if err != nil {
+			continue
+		}
+
+		if stat.pgrp != pgrp {
+			out = append(out, pid)
+		}
+	}
+
+	return out
+}
+
+//tracker accumulates the usage of the descendants of a process. The process objects are kept between samples
+//since they are needed to compute the cpu percent.
+type tracker struct {
+	tracked map[int32]*psutils.Process
+	m       sync.Mutex
+}
+
+func (t *tracker) add(pids []int, stats *ProcessStats) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	tracked := make(map[int32]*psutils.Process)
+	for _, pid := range pids {
+		child, ok := t.tracked[int32(pid)]
+		if !ok {
+			var err error
+			if child, err = psutils.NewProcess(int32(pid)); err != nil {
+				continue
+			}
+		}
+
+		childCPU, err := child.Percent(0)
+		if err != nil {
+			//the process is gone.
+			continue
+		}
+
+		tracked[child.Pid] = child
+		stats.CPU += childCPU
+		childMem, err := child.MemoryInfo()
+		if err == nil {
+			stats.Debug = fmt.Sprintf("%s %d", stats.Debug, child.Pid)
+			stats.RSS += childMem.RSS
+			stats.Swap += childMem.Swap
+			stats.VMS += childMem.VMS
+		} else {
+			log.Errorf("%s", err)
+		}
+	}
+
+	t.tracked = tracked
+}
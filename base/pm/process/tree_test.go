@@ -0,0 +1,34 @@
+package process
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDescendants(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 10 & setsid sleep 10 & wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if !assert.Nil(t, cmd.Start()) {
+		t.Fatal()
+	}
+
+	defer cmd.Wait()
+	pid := cmd.Process.Pid
+	time.Sleep(200 * time.Millisecond)
+
+	pids := descendants(pid)
+	out := escaped(pid, pids)
+
+	syscall.Kill(-pid, syscall.SIGKILL)
+	for _, p := range out {
+		syscall.Kill(p, syscall.SIGKILL)
+	}
+
+	assert.Len(t, pids, 2)
+	assert.NotContains(t, pids, pid)
+	//the setsid sleep left the process group
+	assert.Len(t, out, 1)
+}
@@ -2,117 +2,217 @@ package pm
 
 import (
 	"container/list"
+	"fmt"
 	"github.com/g8os/core0/base/pm/core"
+	"sort"
 	"sync"
 )
 
+/*
+cmdQueue is a named queue of commands, at most `concurrency` commands of the queue run at the same time (1 by
+default, which makes the queue strictly serial).
+*/
+type cmdQueue struct {
+	name        string
+	pending     *list.List
+	running     map[*core.Command]bool
+	concurrency int
+	paused      bool
+}
+
+//QueueInfo describes the state of a queue
+type QueueInfo struct {
+	Name        string   `json:"name"`
+	Concurrency int      `json:"concurrency"`
+	Paused      bool     `json:"paused"`
+	Running     []string `json:"running"`
+	Pending     []string `json:"pending"`
+}
+
 /**
 cmdQueueManager is used for sequential cmds exectuions
 */
 type cmdQueueManager struct {
-	queues   map[string]*list.List
-	signal   chan string
-	consumer chan *core.Command
-	producer chan *core.Command
+	queues   map[string]*cmdQueue
+	dispatch func(*core.Command)
 	lock     sync.Mutex
 }
 
 /*
-NewCmdQueueManager creates a new instace of the queue manager. Normally only the PM should call this.
+NewCmdQueueManager creates a new instace of the queue manager. Normally only the PM should call this. Commands that
+reach the head of their queue are passed to dispatch.
 */
-func newCmdQueueManager() *cmdQueueManager {
-	mgr := &cmdQueueManager{
-		queues:   make(map[string]*list.List),
-		signal:   make(chan string),
-		consumer: make(chan *core.Command),
-		producer: make(chan *core.Command),
+func newCmdQueueManager(dispatch func(*core.Command)) *cmdQueueManager {
+	return &cmdQueueManager{
+		queues:   make(map[string]*cmdQueue),
+		dispatch: dispatch,
 	}
-
-	//start queue dispatcher
-	go mgr.dispatcherLoop()
-
-	//start the producer
-	go mgr.producerLoop()
-
-	return mgr
 }
 
-func (mgr *cmdQueueManager) dispatcherLoop() {
-	for {
-		cmd := <-mgr.consumer
-
-		if cmd.Queue == "" {
-			//not a queued command just log a warning and continue
-			log.Warningf("Queue manager received a command with no set queue (%s)", cmd)
-			continue
-		}
-
-		log.Infof("Pushing command to queue '%s'", cmd.Queue)
-		//push command to correct queue
-		mgr.lock.Lock()
-		queue, ok := mgr.queues[cmd.Queue]
-		if !ok {
-			log.Debugf("Queue '%s' doesn't exist, initializing...", cmd.Queue)
-			queue = list.New()
-			mgr.queues[cmd.Queue] = queue
-		}
-		mgr.lock.Unlock()
-		//push the command to the queue.
-		queue.PushBack(cmd)
-
-		if !ok {
-			//since we just create this queue. We signal that it's ready
-			//to produce the first queued command. Think of it as intial start
-			//condition. When this command exists, it will auto signal the next command
-			//and so on.
-			mgr.signal <- cmd.Queue
+//get gets the queue with the given name, creating it if it doesn't exist
+func (mgr *cmdQueueManager) get(name string) *cmdQueue {
+	queue, ok := mgr.queues[name]
+	if !ok {
+		log.Debugf("Queue '%s' doesn't exist, initializing...", name)
+		queue = &cmdQueue{
+			name:        name,
+			pending:     list.New(),
+			running:     make(map[*core.Command]bool),
+			concurrency: 1,
 		}
+		mgr.queues[name] = queue
 	}
+
+	return queue
 }
 
-//producer gets the next job to run and send it over channel
-func (mgr *cmdQueueManager) producerLoop() {
-	for {
-		queueName := <-mgr.signal
-
-		log.Debugf("Queue '%s' ready...", queueName)
-		mgr.lock.Lock()
-		queue, ok := mgr.queues[queueName]
-		if !ok {
-			//Proceed on a queue that doesn't exist anymore.
-			mgr.lock.Unlock()
-			continue
-		}
+//next pops the commands that can start now, and cleans up the queue if it's not used anymore. Must be called
+//with the lock held.
+func (mgr *cmdQueueManager) next(queue *cmdQueue) []*core.Command {
+	var ready []*core.Command
+	for !queue.paused && len(queue.running) < queue.concurrency && queue.pending.Len() > 0 {
+		cmd := queue.pending.Remove(queue.pending.Front()).(*core.Command)
+		queue.running[cmd] = true
+		ready = append(ready, cmd)
+	}
 
-		if queue.Len() == 0 {
-			//last command on this queue exited successfully.
-			//we can safely delete it.
-			log.Infof("Cleaning up  queue '%s'", queueName)
-			delete(mgr.queues, queueName)
-			mgr.lock.Unlock()
-			continue
-		}
+	if !queue.paused && len(queue.running) == 0 && queue.pending.Len() == 0 {
+		//last command on this queue exited, we can safely delete it.
+		log.Infof("Cleaning up  queue '%s'", queue.name)
+		delete(mgr.queues, queue.name)
+	}
 
-		mgr.lock.Unlock()
+	return ready
+}
 
-		next := queue.Remove(queue.Front()).(*core.Command)
-		mgr.producer <- next
+func (mgr *cmdQueueManager) run(cmds []*core.Command) {
+	for _, cmd := range cmds {
+		mgr.dispatch(cmd)
 	}
 }
 
 func (mgr *cmdQueueManager) Push(cmd *core.Command) {
-	mgr.consumer <- cmd
+	if cmd.Queue == "" {
+		//not a queued command just log a warning and continue
+		log.Warningf("Queue manager received a command with no set queue (%s)", cmd)
+		return
+	}
+
+	log.Infof("Pushing command to queue '%s'", cmd.Queue)
+
+	mgr.lock.Lock()
+	queue := mgr.get(cmd.Queue)
+	if cmd.QueueConcurrency > 0 {
+		queue.concurrency = cmd.QueueConcurrency
+	}
+	queue.pending.PushBack(cmd)
+	ready := mgr.next(queue)
+	mgr.lock.Unlock()
+
+	mgr.run(ready)
 }
 
+//Notify must be called when a command that was dispatched by the queue manager exits
 func (mgr *cmdQueueManager) Notify(cmd *core.Command) {
 	if cmd.Queue == "" {
 		//nothing to do
 		return
 	}
 
-	mgr.signal <- cmd.Queue
+	mgr.lock.Lock()
+	queue, ok := mgr.queues[cmd.Queue]
+	if !ok || !queue.running[cmd] {
+		mgr.lock.Unlock()
+		return
+	}
+
+	delete(queue.running, cmd)
+	ready := mgr.next(queue)
+	mgr.lock.Unlock()
+
+	mgr.run(ready)
+}
+
+//Cancel removes a pending command from its queue, the command is returned so it can be reported as cancelled.
+func (mgr *cmdQueueManager) Cancel(id string) (*core.Command, error) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+
+	for _, queue := range mgr.queues {
+		for e := queue.pending.Front(); e != nil; e = e.Next() {
+			cmd := e.Value.(*core.Command)
+			if cmd.ID != id {
+				continue
+			}
+
+			queue.pending.Remove(e)
+			//nothing else is started here, but the queue may need a cleanup.
+			mgr.next(queue)
+			return cmd, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no queued command with id '%s'", id)
+}
+
+//Pause stops dispatching the commands of the queue, running commands are not affected.
+func (mgr *cmdQueueManager) Pause(name string) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+
+	mgr.get(name).paused = true
+}
+
+//Resume resumes dispatching the commands of a paused queue
+func (mgr *cmdQueueManager) Resume(name string) error {
+	mgr.lock.Lock()
+	queue, ok := mgr.queues[name]
+	if !ok {
+		mgr.lock.Unlock()
+		return fmt.Errorf("queue '%s' doesn't exist", name)
+	}
+
+	queue.paused = false
+	ready := mgr.next(queue)
+	mgr.lock.Unlock()
+
+	mgr.run(ready)
+	return nil
 }
 
-func (mgr *cmdQueueManager) Producer() <-chan *core.Command {
-	return mgr.producer
+//List gets the state of all queues
+func (mgr *cmdQueueManager) List() []QueueInfo {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+
+	queues := make([]QueueInfo, 0, len(mgr.queues))
+	for _, queue := range mgr.queues {
+		info := QueueInfo{
+			Name:        queue.name,
+			Concurrency: queue.concurrency,
+			Paused:      queue.paused,
+			Running:     make([]string, 0, len(queue.running)),
+			Pending:     make([]string, 0, queue.pending.Len()),
+		}
+
+		for cmd := range queue.running {
+			info.Running = append(info.Running, cmd.ID)
+		}
+		sort.Strings(info.Running)
+
+		for e := queue.pending.Front(); e != nil; e = e.Next() {
+			info.Pending = append(info.Pending, e.Value.(*core.Command).ID)
+		}
+
+		queues = append(queues, info)
+	}
+
+	sort.Sort(queueInfos(queues))
+	return queues
 }
+
+type queueInfos []QueueInfo
+
+func (q queueInfos) Len() int           { return len(q) }
+func (q queueInfos) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q queueInfos) Less(i, j int) bool { return q[i].Name < q[j].Name }
@@ -0,0 +1,68 @@
+package pm
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func queued(id string, queue string, concurrency int) *core.Command {
+	return &core.Command{ID: id, Queue: queue, QueueConcurrency: concurrency}
+}
+
+func TestQueueSerial(t *testing.T) {
+	var started []string
+	mgr := newCmdQueueManager(func(cmd *core.Command) {
+		started = append(started, cmd.ID)
+	})
+
+	first := queued("1", "q", 0)
+	mgr.Push(first)
+	mgr.Push(queued("2", "q", 0))
+	assert.Equal(t, []string{"1"}, started)
+
+	mgr.Notify(first)
+	assert.Equal(t, []string{"1", "2"}, started)
+}
+
+func TestQueueConcurrency(t *testing.T) {
+	var started []string
+	mgr := newCmdQueueManager(func(cmd *core.Command) {
+		started = append(started, cmd.ID)
+	})
+
+	mgr.Push(queued("1", "q", 2))
+	mgr.Push(queued("2", "q", 0))
+	mgr.Push(queued("3", "q", 0))
+	assert.Equal(t, []string{"1", "2"}, started)
+
+	queues := mgr.List()
+	if assert.Len(t, queues, 1) {
+		assert.Equal(t, 2, queues[0].Concurrency)
+		assert.Equal(t, []string{"1", "2"}, queues[0].Running)
+		assert.Equal(t, []string{"3"}, queues[0].Pending)
+	}
+}
+
+func TestQueuePauseCancel(t *testing.T) {
+	var started []string
+	mgr := newCmdQueueManager(func(cmd *core.Command) {
+		started = append(started, cmd.ID)
+	})
+
+	mgr.Pause("q")
+	mgr.Push(queued("1", "q", 0))
+	mgr.Push(queued("2", "q", 0))
+	assert.Empty(t, started)
+
+	cmd, err := mgr.Cancel("1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1", cmd.ID)
+	}
+
+	_, err = mgr.Cancel("1")
+	assert.Error(t, err)
+
+	assert.Nil(t, mgr.Resume("q"))
+	assert.Equal(t, []string{"2"}, started)
+}
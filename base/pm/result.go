@@ -0,0 +1,102 @@
+package pm
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"gopkg.in/yaml.v2"
+)
+
+var resultFormats = map[int]string{
+	stream.LevelResultJSON: core.ResultFormatJSON,
+	stream.LevelResultYAML: core.ResultFormatYAML,
+	stream.LevelResultTOML: core.ResultFormatTOML,
+	stream.LevelResultHRD:  core.ResultFormatHRD,
+	stream.LevelResultJob:  core.ResultFormatJSON,
+}
+
+//jsonable converts the maps decoded by yaml (which can have non string keys) to maps that can be encoded to json
+func jsonable(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = jsonable(elem)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = jsonable(elem)
+		}
+		return v
+	}
+
+	return value
+}
+
+//toJSON converts the result data of the given format to json
+func toJSON(format string, data string) (string, error) {
+	var value interface{}
+	var err error
+
+	switch format {
+	case core.ResultFormatJSON:
+		if !json.Valid([]byte(data)) {
+			return "", fmt.Errorf("invalid json")
+		}
+		return data, nil
+	case core.ResultFormatYAML:
+		err = yaml.Unmarshal([]byte(data), &value)
+		value = jsonable(value)
+	case core.ResultFormatTOML:
+		var table map[string]interface{}
+		_, err = toml.Decode(data, &table)
+		value = table
+	default:
+		return "", fmt.Errorf("unknown result format '%s'", format)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+/*
+setResultData sets the result message on the job result. The data is always converted to json, the original format
+is kept in Format and the original data in RawData (if it was converted). If the data can't be parsed, Data is set to
+the original data as a json string and the error is reported in ParseError. HRD results are not parsed, they are
+passed through as a json string.
+*/
+func setResultData(result *core.JobResult, msg *stream.Message) {
+	result.Level = msg.Level
+	result.Format = resultFormats[msg.Level]
+
+	if result.Format == core.ResultFormatHRD {
+		raw, _ := json.Marshal(msg.Message)
+		result.Data = string(raw)
+		result.RawData = msg.Message
+		return
+	}
+
+	data, err := toJSON(result.Format, msg.Message)
+	if err != nil {
+		result.ParseError = fmt.Sprintf("failed to parse %s result: %s", result.Format, err)
+		raw, _ := json.Marshal(msg.Message)
+		result.Data = string(raw)
+		return
+	}
+
+	result.Data = data
+	if result.Format != core.ResultFormatJSON {
+		result.RawData = msg.Message
+	}
+}
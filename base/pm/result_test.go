@@ -0,0 +1,57 @@
+package pm
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResultDataJSON(t *testing.T) {
+	var result core.JobResult
+	setResultData(&result, &stream.Message{Level: stream.LevelResultJSON, Message: `{"a": 1}`})
+
+	assert.Equal(t, `{"a": 1}`, result.Data)
+	assert.Equal(t, core.ResultFormatJSON, result.Format)
+	assert.Empty(t, result.RawData)
+	assert.Empty(t, result.ParseError)
+}
+
+func TestResultDataYAML(t *testing.T) {
+	var result core.JobResult
+	data := "a: 1\nb:\n  - x\n  - 2: z\n"
+	setResultData(&result, &stream.Message{Level: stream.LevelResultYAML, Message: data})
+
+	assert.JSONEq(t, `{"a": 1, "b": ["x", {"2": "z"}]}`, result.Data)
+	assert.Equal(t, core.ResultFormatYAML, result.Format)
+	assert.Equal(t, data, result.RawData)
+	assert.Empty(t, result.ParseError)
+}
+
+func TestResultDataTOML(t *testing.T) {
+	var result core.JobResult
+	setResultData(&result, &stream.Message{Level: stream.LevelResultTOML, Message: "a = 1\n[b]\nc = \"x\"\n"})
+
+	assert.JSONEq(t, `{"a": 1, "b": {"c": "x"}}`, result.Data)
+	assert.Equal(t, core.ResultFormatTOML, result.Format)
+	assert.Empty(t, result.ParseError)
+}
+
+func TestResultDataParseError(t *testing.T) {
+	var result core.JobResult
+	setResultData(&result, &stream.Message{Level: stream.LevelResultJSON, Message: "not json"})
+
+	assert.Equal(t, `"not json"`, result.Data)
+	assert.Equal(t, stream.LevelResultJSON, result.Level)
+	assert.NotEmpty(t, result.ParseError)
+}
+
+func TestResultDataHRD(t *testing.T) {
+	var result core.JobResult
+	setResultData(&result, &stream.Message{Level: stream.LevelResultHRD, Message: "a = 1\n"})
+
+	assert.Equal(t, `"a = 1\n"`, result.Data)
+	assert.Equal(t, core.ResultFormatHRD, result.Format)
+	assert.Equal(t, "a = 1\n", result.RawData)
+	assert.Empty(t, result.ParseError)
+}
@@ -1,21 +1,35 @@
 package pm
 
 import (
+	"context"
 	"fmt"
 	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/cron"
 	"github.com/g8os/core0/base/pm/process"
 	"github.com/g8os/core0/base/pm/stream"
 	"github.com/g8os/core0/base/stats"
 	"github.com/g8os/core0/base/utils"
+	"io"
+	"math/rand"
+	"regexp"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
 const (
 	StreamBufferSize = 1000
 
+	//DefaultRestartBackoff is the wait before the first restart of a failing command
+	DefaultRestartBackoff = 1 * time.Second
+	//DefaultRestartMaxBackoff is the max wait between restarts of a failing command
+	DefaultRestartMaxBackoff = 60 * time.Second
+	//RestartStablePeriod if a command ran for that long, its restart counter is reset.
+	RestartStablePeriod = 60 * time.Second
+	//CrashLoopThreshold number of consecutive restarts (with no stable run) after which the command is
+	//considered crash looping
+	CrashLoopThreshold = 5
+
 	meterPeriod = 30 * time.Second
 )
 
@@ -25,6 +39,11 @@ type Runner interface {
 	Kill()
 	Process() process.Process
 	Wait() *core.JobResult
+	NextRun() time.Time
+	StartTime() time.Time
+	CrashLoop() bool
+	Stdin() (io.WriteCloser, error)
+	Health() *HealthStatus
 }
 
 type runnerImpl struct {
@@ -32,15 +51,32 @@ type runnerImpl struct {
 	command *core.Command
 	factory process.ProcessFactory
 	kill    chan int
+	done    chan struct{}
 
 	process process.Process
 	statsd  *stats.Statsd
 
 	hooks []RunnerHook
 
+	failureMatch *regexp.Regexp
+
 	waitOnce sync.Once
 	result   *core.JobResult
 	wg       sync.WaitGroup
+
+	next    time.Time
+	nextMux sync.Mutex
+
+	started    time.Time
+	startedMux sync.Mutex
+
+	//runs is the number of consecutive restarts with no stable run
+	runs         int
+	crashLoop    bool
+	crashLoopMux sync.Mutex
+
+	health    *healthProbe
+	healthMux sync.Mutex
 }
 
 /*
@@ -72,6 +108,7 @@ func NewRunner(manager *PM, command *core.Command, factory process.ProcessFactor
 		command: command,
 		factory: factory,
 		kill:    make(chan int),
+		done:    make(chan struct{}),
 		hooks:   hooks,
 
 		statsd: stats.NewStatsd(
@@ -117,6 +154,15 @@ func (runner *runnerImpl) run() *core.JobResult {
 	process := runner.process
 
 	starttime := time.Now()
+	runner.startedMux.Lock()
+	runner.started = starttime
+	runner.startedMux.Unlock()
+
+	defer func() {
+		runner.startedMux.Lock()
+		runner.started = time.Time{}
+		runner.startedMux.Unlock()
+	}()
 
 	channel, err := process.Run()
 	jobresult := core.NewBasicJobResult(runner.command)
@@ -143,12 +189,35 @@ func (runner *runnerImpl) run() *core.JobResult {
 	stdoutBuffer := stream.NewBuffer(StreamBufferSize)
 	stderrBuffer := stream.NewBuffer(StreamBufferSize)
 
+	var capture *jobCapture
+	if runner.manager.capture != nil && !runner.command.Silent {
+		capture = runner.manager.capture.open(runner.command.ID)
+		defer capture.Close()
+	}
+
 	timeout := runner.timeout()
+	idleTimeout := time.Duration(runner.command.IdleTimeout) * time.Second
+	lastActivity := starttime
 	meterTicker := time.NewTicker(meterPeriod)
 	defer meterTicker.Stop()
 
 	handlersTicker := time.NewTicker(1 * time.Second)
 	defer handlersTicker.Stop()
+
+	var unhealthy chan error
+	if runner.command.HealthCheck != nil {
+		probe := newHealthProbe(runner.manager, runner.command)
+		runner.healthMux.Lock()
+		runner.health = probe
+		runner.healthMux.Unlock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		//buffered so the probe never blocks if the process exits at the same time.
+		unhealthy = make(chan error, 1)
+		go probe.run(ctx, unhealthy)
+	}
 loop:
 	for {
 		select {
@@ -160,6 +229,16 @@ loop:
 			process.Kill()
 			jobresult.State = core.StateTimeout
 			break loop
+		case err := <-unhealthy:
+			log.Errorf("Command %s is unhealthy: %s", runner.command, err)
+			process.Kill()
+			jobresult.State = core.StateUnhealthy
+			critical = err.Error()
+			runner.manager.msgCallback(runner.command, &stream.Message{
+				Level:   stream.LevelCritical,
+				Message: critical,
+			})
+			break loop
 		case <-meterTicker.C:
 			runner.meter()
 		case <-handlersTicker.C:
@@ -167,7 +246,16 @@ loop:
 			for _, hook := range runner.hooks {
 				go hook.Tick(d)
 			}
+
+			if idleTimeout > 0 && time.Since(lastActivity) > idleTimeout {
+				log.Errorf("Command %s didn't output anything for %s", runner.command, idleTimeout)
+				process.Kill()
+				jobresult.State = core.StateIdleTimeout
+				critical = fmt.Sprintf("no output for %s", idleTimeout)
+				break loop
+			}
 		case message := <-channel:
+			lastActivity = time.Now()
 			if utils.In(stream.ResultMessageLevels, message.Level) {
 				result = message
 			} else if message.Level == stream.LevelExitState {
@@ -177,7 +265,13 @@ loop:
 				stdoutBuffer.Append(message.Message)
 			} else if message.Level == stream.LevelStderr {
 				stderrBuffer.Append(message.Message)
-			} else if message.Level == stream.LevelStatsd {
+			}
+
+			if capture != nil {
+				capture.Write(message.Level, message.Message)
+			}
+
+			if message.Level == stream.LevelStatsd {
 				runner.statsd.Feed(strings.Trim(message.Message, " "))
 			} else if message.Level == stream.LevelCritical {
 				critical = message.Message
@@ -189,6 +283,14 @@ loop:
 
 			//by default, all messages are forwarded to the manager for further processing.
 			runner.manager.msgCallback(runner.command, message)
+
+			if runner.failed(message) {
+				log.Errorf("Command %s output matched the failure pattern", runner.command)
+				process.Kill()
+				jobresult.State = core.StateFailureMatch
+				critical = fmt.Sprintf("output matched failure pattern: %s", message.Message)
+				break loop
+			}
 		}
 	}
 
@@ -200,8 +302,7 @@ loop:
 	}
 
 	if result != nil {
-		jobresult.Level = result.Level
-		jobresult.Data = result.Message
+		setResultData(jobresult, result)
 	}
 
 	jobresult.Streams = []string{
@@ -211,30 +312,251 @@ loop:
 
 	jobresult.Critical = critical
 
+	jobresult.Exit = exitStatus(process)
+
 	return jobresult
 }
 
+//failed checks if the message is an output line that matches the failure pattern of the command
+func (runner *runnerImpl) failed(message *stream.Message) bool {
+	if runner.failureMatch == nil {
+		return false
+	}
+
+	if message.Level == stream.LevelExitState || message.Level == stream.LevelStatsd ||
+		utils.In(stream.ResultMessageLevels, message.Level) {
+		return false
+	}
+
+	return runner.failureMatch.MatchString(message.Message)
+}
+
+//exitStatus gets the exit status of the process if it supports it
+func exitStatus(ps process.Process) *core.ExitStatus {
+	if exit, ok := ps.(process.ExitProcess); ok {
+		return exit.ExitStatus()
+	}
+
+	return nil
+}
+
+//backoff calculates the wait before the nth restart, it doubles on each restart starting from initial
+//and never exceeds max
+func backoff(initial, max time.Duration, n int) time.Duration {
+	wait := initial
+	for i := 1; i < n && wait < max; i++ {
+		wait *= 2
+	}
+
+	if wait > max {
+		wait = max
+	}
+
+	return wait
+}
+
+func (runner *runnerImpl) restartBackoff(n int) time.Duration {
+	initial := DefaultRestartBackoff
+	if runner.command.RestartBackoff > 0 {
+		initial = time.Duration(runner.command.RestartBackoff) * time.Second
+	}
+
+	max := DefaultRestartMaxBackoff
+	if runner.command.RestartMaxBackoff > 0 {
+		max = time.Duration(runner.command.RestartMaxBackoff) * time.Second
+	}
+
+	if max < initial {
+		max = initial
+	}
+
+	return backoff(initial, max, n)
+}
+
+func (runner *runnerImpl) setCrashLoop(crashLoop bool) {
+	runner.crashLoopMux.Lock()
+	defer runner.crashLoopMux.Unlock()
+	runner.crashLoop = crashLoop
+}
+
+/*
+restart tells if the command must be restarted after a run that lasted for elapsed, and how long to wait before the
+restart. It keeps track of the consecutive restarts to detect crash loops, whatever max_restart is. A command that
+runs out of restarts keeps the state of its last run.
+*/
+func (runner *runnerImpl) restart(result *core.JobResult, elapsed time.Duration) (bool, time.Duration) {
+	if elapsed >= RestartStablePeriod {
+		//the process ran long enough to be considered healthy, so we start counting from scratch.
+		runner.runs = 0
+		runner.setCrashLoop(false)
+	}
+
+	if !runner.shouldRestart(result) {
+		return false, 0
+	}
+
+	runner.runs++
+	if runner.runs >= CrashLoopThreshold && !runner.CrashLoop() {
+		runner.setCrashLoop(true)
+		log.Errorf("Command %s is crash looping", runner.command)
+		runner.manager.msgCallback(runner.command, &stream.Message{
+			Level:   stream.LevelCritical,
+			Message: fmt.Sprintf("crash loop detected, restarted %d times in a row", runner.runs),
+		})
+	}
+
+	if runner.command.MaxRestart <= 0 || runner.runs < runner.command.MaxRestart {
+		log.Infof("Restarting '%s' due to exit status %s, trials: %d/%d", runner.command, result.State, runner.runs+1, runner.command.MaxRestart)
+		return true, runner.restartBackoff(runner.runs)
+	}
+
+	return false, 0
+}
+
+func (runner *runnerImpl) shouldRestart(result *core.JobResult) bool {
+	if result.State == core.StateUnhealthy {
+		//the job was killed because it's unhealthy, so it must come back whatever the policy is
+		return true
+	}
+
+	switch runner.command.GetRestartPolicy() {
+	case core.RestartPolicyAlways:
+		return true
+	case core.RestartPolicyOnFailure:
+		return result.State != core.StateSuccess
+	}
+
+	return false
+}
+
+//jitter gets a random delay in the range of the command schedule jitter
+func (runner *runnerImpl) jitter() time.Duration {
+	if runner.command.ScheduleJitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(time.Duration(runner.command.ScheduleJitter) * time.Second)))
+}
+
+/*
+nextFire gets the next time the scheduled command should run, last is the last time the command was
+scheduled to run. If the command was still running when it was supposed to fire again, it either runs
+immediately or waits for the next fire time if skip_if_running is set.
+*/
+func (runner *runnerImpl) nextFire(schedule *cron.Schedule, last time.Time) time.Time {
+	now := time.Now()
+	next := schedule.Next(last)
+	if next.IsZero() || !next.Before(now) {
+		return next
+	}
+
+	if runner.command.SkipIfRunning {
+		log.Infof("Skipping missed runs of '%s'", runner.command)
+		return schedule.Next(now)
+	}
+
+	return now
+}
+
+//sleep waits for the given duration, it returns false if the runner was killed during the wait
+func (runner *runnerImpl) sleep(d time.Duration) bool {
+	runner.nextMux.Lock()
+	runner.next = time.Now().Add(d)
+	runner.nextMux.Unlock()
+
+	defer func() {
+		runner.nextMux.Lock()
+		runner.next = time.Time{}
+		runner.nextMux.Unlock()
+	}()
+
+	select {
+	case <-time.After(d):
+		return true
+	case <-runner.kill:
+		return false
+	}
+}
+
 func (runner *runnerImpl) Run() {
-	runs := 0
 	var result *core.JobResult
+	var schedule *cron.Schedule
+	var fire time.Time
+
 	defer func() {
 		runner.statsd.Stop()
 		if result != nil {
 			runner.result = result
 			runner.manager.resultCallback(runner.command, result)
+		}
+
+		runner.manager.cleanUp(runner)
+		close(runner.done)
 
+		//waiters are only released once the runner is removed from the manager, so the same id can be run again
+		if result != nil {
 			runner.waitOnce.Do(func() {
 				runner.wg.Done()
 			})
 		}
-
-		runner.manager.cleanUp(runner)
 	}()
 
+	if check := runner.command.HealthCheck; check != nil {
+		if err := check.Validate(); err != nil {
+			result = core.NewBasicJobResult(runner.command)
+			result.State = core.StateError
+			result.Data = fmt.Sprintf("invalid health check: %s", err)
+			return
+		}
+	}
+
+	if err := core.ValidateRestartPolicy(runner.command.RestartPolicy); err != nil {
+		result = core.NewBasicJobResult(runner.command)
+		result.State = core.StateError
+		result.Data = err.Error()
+		return
+	}
+
+	if runner.command.FailureMatch != "" {
+		var err error
+		if runner.failureMatch, err = regexp.Compile(runner.command.FailureMatch); err != nil {
+			result = core.NewBasicJobResult(runner.command)
+			result.State = core.StateError
+			result.Data = fmt.Sprintf("invalid failure match '%s': %s", runner.command.FailureMatch, err)
+			return
+		}
+	}
+
+	if runner.command.Schedule != "" {
+		var err error
+		schedule, err = cron.Parse(runner.command.Schedule)
+		if err == nil {
+			fire = runner.nextFire(schedule, time.Now())
+			if fire.IsZero() {
+				err = fmt.Errorf("schedule never fires")
+			}
+		}
+
+		if err != nil {
+			result = core.NewBasicJobResult(runner.command)
+			result.State = core.StateError
+			result.Data = fmt.Sprintf("invalid schedule '%s': %s", runner.command.Schedule, err)
+			return
+		}
+
+		log.Infof("Scheduling '%s' at %s", runner.command, fire)
+		if !runner.sleep(fire.Sub(time.Now()) + runner.jitter()) {
+			log.Infof("Command %s Killed during scheduler sleep", runner.command)
+			result = core.NewBasicJobResult(runner.command)
+			result.State = core.StateKilled
+			return
+		}
+	}
+
 	//start statsd
 	runner.statsd.Run()
-loop:
 	for {
+		starttime := time.Now()
 		result = runner.run()
 
 		for _, hook := range runner.hooks {
@@ -246,31 +568,29 @@ loop:
 			break
 		}
 
-		restarting := false
-		var restartIn time.Duration
-
-		if result.State != core.StateSuccess && runner.command.MaxRestart > 0 {
-			runs++
-			if runs < runner.command.MaxRestart {
-				log.Infof("Restarting '%s' due to upnormal exit status, trials: %d/%d", runner.command, runs+1, runner.command.MaxRestart)
-				restarting = true
-				restartIn = 1 * time.Second
-			}
-		}
+		restarting, restartIn := runner.restart(result, time.Since(starttime))
 
 		if runner.command.RecurringPeriod > 0 {
 			restarting = true
 			restartIn = time.Duration(runner.command.RecurringPeriod) * time.Second
 		}
 
+		if schedule != nil {
+			fire = runner.nextFire(schedule, fire)
+			if fire.IsZero() {
+				break
+			}
+
+			restarting = true
+			restartIn = fire.Sub(time.Now()) + runner.jitter()
+		}
+
 		if restarting {
-			log.Infof("Recurring '%s' in %d", runner.command, restartIn)
-			select {
-			case <-time.After(restartIn):
-			case <-runner.kill:
+			log.Infof("Recurring '%s' in %s", runner.command, restartIn)
+			if !runner.sleep(restartIn) {
 				log.Infof("Command %s Killed during scheduler sleep", runner.command)
 				result.State = core.StateKilled
-				break loop
+				break
 			}
 		} else {
 			break
@@ -280,13 +600,72 @@ loop:
 }
 
 func (runner *runnerImpl) Kill() {
-	runner.kill <- 1
+	select {
+	case runner.kill <- 1:
+	case <-runner.done:
+		//runner already exited, nothing to kill
+	}
+}
+
+//NextRun gets the time of the next run if the runner is waiting for a schedule or a restart
+func (runner *runnerImpl) NextRun() time.Time {
+	runner.nextMux.Lock()
+	defer runner.nextMux.Unlock()
+	return runner.next
+}
+
+//StartTime gets the time the current run started, zero if the command is not running
+func (runner *runnerImpl) StartTime() time.Time {
+	runner.startedMux.Lock()
+	defer runner.startedMux.Unlock()
+	return runner.started
+}
+
+//CrashLoop tells if the command is crash looping, it's reset once the command runs long enough
+func (runner *runnerImpl) CrashLoop() bool {
+	runner.crashLoopMux.Lock()
+	defer runner.crashLoopMux.Unlock()
+	return runner.crashLoop
+}
+
+//Stdin gets the stdin of the running process, only available if the process was started with keep_stdin
+func (runner *runnerImpl) Stdin() (io.WriteCloser, error) {
+	ps := runner.process
+	if ps == nil {
+		return nil, fmt.Errorf("process is not running")
+	}
+
+	sp, ok := ps.(process.StdinProcess)
+	if !ok {
+		return nil, fmt.Errorf("process doesn't accept stdin")
+	}
+
+	stdin := sp.Stdin()
+	if stdin == nil {
+		return nil, fmt.Errorf("process stdin is not open, process must be started with keep_stdin")
+	}
+
+	return stdin, nil
+}
+
+//Health gets the status of the health probe of the current run, nil if the command has no health check
+func (runner *runnerImpl) Health() *HealthStatus {
+	runner.healthMux.Lock()
+	defer runner.healthMux.Unlock()
+
+	if runner.health == nil {
+		return nil
+	}
+
+	status := runner.health.Status()
+	return &status
 }
 
 func (runner *runnerImpl) Process() process.Process {
 	return runner.process
 }
 
+//Wait waits for the runner to exit, once it returns the job id is free to be run again
 func (runner *runnerImpl) Wait() *core.JobResult {
 	runner.wg.Wait()
 	return runner.result
@@ -309,6 +688,6 @@ func (runner *runnerImpl) Register(g process.GetPID) error {
 	})
 }
 
-func (runner *runnerImpl) WaitPID(pid int) *syscall.WaitStatus {
+func (runner *runnerImpl) WaitPID(pid int) *process.ProcessState {
 	return runner.manager.WaitPID(pid)
 }
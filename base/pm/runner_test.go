@@ -0,0 +1,168 @@
+package pm
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/pm/process"
+	"github.com/stretchr/testify/assert"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+
+	for i, e := range expected {
+		assert.Equal(t, e, backoff(1*time.Second, 10*time.Second, i+1))
+	}
+}
+
+func TestBackoffOverflow(t *testing.T) {
+	assert.Equal(t, 60*time.Second, backoff(1*time.Second, 60*time.Second, 1000))
+}
+
+func TestRestartCrashLoop(t *testing.T) {
+	const short, long = time.Second, 2 * RestartStablePeriod
+
+	type run struct {
+		state   string
+		elapsed time.Duration
+	}
+
+	cases := []struct {
+		name       string
+		policy     string
+		maxRestart int
+		runs       []run
+		restarts   int
+		state      string
+		crashLoop  bool
+	}{
+		{"max restart below threshold", "", 3, []run{{core.StateError, short}, {core.StateError, short}, {core.StateError, short}}, 2, core.StateError, false},
+		{"max restart above threshold", "", 6, []run{{core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}}, 5, core.StateError, true},
+		{"single failure", "", 1, []run{{core.StateError, short}}, 0, core.StateError, false},
+		{"never", core.RestartPolicyNever, 0, []run{{core.StateError, short}}, 0, core.StateError, false},
+		{"recovered", core.RestartPolicyOnFailure, 3, []run{{core.StateError, short}, {core.StateSuccess, short}}, 1, core.StateSuccess, false},
+		{"always", core.RestartPolicyAlways, 0, []run{{core.StateSuccess, short}, {core.StateError, short}, {core.StateSuccess, short}, {core.StateError, short}, {core.StateSuccess, short}}, 5, core.StateSuccess, true},
+		{"stable run", core.RestartPolicyAlways, 0, []run{{core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, long}}, 6, core.StateError, false},
+		{"unhealthy", "", 0, []run{{core.StateUnhealthy, short}}, 1, core.StateUnhealthy, false},
+		{"unlimited", core.RestartPolicyOnFailure, 0, []run{{core.StateError, short}, {core.StateError, short}, {core.StateError, short}, {core.StateError, short}}, 4, core.StateError, false},
+	}
+
+	for _, c := range cases {
+		cmd := &core.Command{ID: c.name, RestartPolicy: c.policy, MaxRestart: c.maxRestart}
+		runner := &runnerImpl{manager: &PM{}, command: cmd}
+
+		restarts := 0
+		var result *core.JobResult
+		for _, r := range c.runs {
+			result = &core.JobResult{State: r.state}
+			if restart, _ := runner.restart(result, r.elapsed); restart {
+				restarts++
+			}
+		}
+
+		assert.Equal(t, c.restarts, restarts, c.name)
+		assert.Equal(t, c.state, result.State, c.name)
+		assert.Equal(t, c.crashLoop, runner.CrashLoop(), c.name)
+	}
+}
+
+//waitTable waits the processes itself, the tests don't run the process manager reaper
+type waitTable struct{}
+
+func (waitTable) Register(g process.GetPID) error {
+	_, err := g()
+	return err
+}
+
+func (waitTable) WaitPID(pid int) *process.ProcessState {
+	var state process.ProcessState
+	syscall.Wait4(pid, &state.Status, 0, &state.Rusage)
+	return &state
+}
+
+//runShell runs the shell script as a job to the end and returns its result
+func runShell(t *testing.T, cmd *core.Command, script string) *core.JobResult {
+	cmd.Command = process.CommandSystem
+	cmd.Arguments = core.MustArguments(process.SystemCommandArguments{
+		Name: "sh",
+		Args: []string{"-c", script},
+	})
+
+	factory := func(_ process.PIDTable, cmd *core.Command) process.Process {
+		return process.NewSystemProcess(waitTable{}, cmd)
+	}
+
+	runner := NewRunner(InitProcessManager(10), cmd, factory)
+	go runner.Run()
+
+	done := make(chan *core.JobResult, 1)
+	go func() {
+		done <- runner.Wait()
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-time.After(10 * time.Second):
+		runner.Kill()
+		t.Fatalf("job %s didn't exit", cmd.ID)
+	}
+
+	return nil
+}
+
+func TestRunnerIdleTimeout(t *testing.T) {
+	result := runShell(t, &core.Command{ID: "idle", IdleTimeout: 1}, "echo started; sleep 10")
+	assert.Equal(t, core.StateIdleTimeout, result.State)
+	assert.Contains(t, result.Critical, "no output")
+	assert.Equal(t, "started\n", result.Streams[0])
+
+	//regular output keeps the job alive
+	result = runShell(t, &core.Command{ID: "active", IdleTimeout: 1}, "for i in 1 2 3 4; do echo $i; sleep 0.5; done")
+	assert.Equal(t, core.StateSuccess, result.State)
+}
+
+func TestRunnerFailureMatch(t *testing.T) {
+	result := runShell(t, &core.Command{ID: "failure", FailureMatch: "^fatal:"},
+		"echo started; echo 'fatal: broken' >&2; sleep 10")
+	assert.Equal(t, core.StateFailureMatch, result.State)
+	assert.Contains(t, result.Critical, "fatal: broken")
+
+	//lines that don't match don't fail the job
+	result = runShell(t, &core.Command{ID: "no-failure", FailureMatch: "^fatal:"}, "echo 'not fatal: ok' >&2")
+	assert.Equal(t, core.StateSuccess, result.State)
+}
+
+func TestRunnerWaitCleanUp(t *testing.T) {
+	mgr := InitProcessManager(10)
+	factory := func(_ process.PIDTable, cmd *core.Command) process.Process {
+		return process.NewSystemProcess(waitTable{}, cmd)
+	}
+
+	//the same id is run again as soon as Wait returns, like a service restart
+	for i := 0; i < 20; i++ {
+		cmd := &core.Command{
+			ID:        "same",
+			Command:   process.CommandSystem,
+			Arguments: core.MustArguments(process.SystemCommandArguments{Name: "true"}),
+		}
+
+		runner, err := mgr.NewRunner(cmd, factory)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		runner.Wait()
+		_, ok := mgr.Runner("same")
+		assert.False(t, ok)
+	}
+}
@@ -55,7 +55,8 @@ func (consumer *consumerImpl) consume(handler MessageHandler) {
 			return
 		}
 
-		line = strings.TrimRight(line, "\n")
+		//tty outputs ends lines with \r\n
+		line = strings.TrimRight(line, "\r\n")
 
 		if line != "" {
 			if !multiline {
@@ -1,6 +1,7 @@
 package pm
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -19,6 +20,7 @@ type stateW struct {
 
 type StateMachine interface {
 	Wait(key ...string) bool
+	WaitContext(ctx context.Context, key string) (bool, error)
 	WaitAll()
 	Release(ket string, state bool) error
 }
@@ -57,17 +59,45 @@ func (s *waitMachineImpl) Wait(keys ...string) bool {
 	return r
 }
 
+//WaitContext waits for the key to be released, the context error is returned if it's done first
+func (s *waitMachineImpl) WaitContext(ctx context.Context, key string) (bool, error) {
+	w, ok := s.keys[key]
+	if !ok {
+		return false, fmt.Errorf("key not found")
+	}
+
+	//a released key wins over a done context
+	select {
+	case <-w.ch:
+		return w.s, nil
+	default:
+	}
+
+	select {
+	case <-w.ch:
+		return w.s, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
 func (s *waitMachineImpl) Release(key string, state bool) error {
 	w, ok := s.keys[key]
 	if !ok {
 		return fmt.Errorf("key not found")
 	}
 
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	select {
+	case <-w.ch:
+		//only the first release counts, the state must not change under the waiters
+		return nil
+	default:
+	}
+
 	w.s = state
-	defer func() {
-		//recover from closing a closed channel
-		recover()
-	}()
 	close(w.ch)
 
 	return nil
@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"path"
+	"strings"
+)
+
+/*
+Policy restricts the commands a sink can run. Command names are matched against glob patterns (ex: `info.*`), a
+command is rejected if it matches a Deny pattern, or if Allow is set and it matches none of its patterns.
+*/
+type Policy struct {
+	Allow     []string
+	Deny      []string
+	Arguments []ArgumentConstraint
+}
+
+/*
+ArgumentConstraint restricts an argument of the commands that match the Command pattern, the argument (if given) must
+match one of the Values patterns. All the elements of list arguments must match. The argument name is matched
+whatever its case, like the commands load their arguments.
+*/
+type ArgumentConstraint struct {
+	Command  string
+	Argument string
+	Values   []string
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Validate checks the policy patterns
+func (p *Policy) Validate() error {
+	patterns := append(append([]string{}, p.Allow...), p.Deny...)
+	for _, constraint := range p.Arguments {
+		if constraint.Command == "" || constraint.Argument == "" {
+			return fmt.Errorf("argument constraints require a command and an argument")
+		}
+
+		patterns = append(append(patterns, constraint.Command), constraint.Values...)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %s", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) checkArgument(name string, value interface{}, patterns []string) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+
+		if !matchAny(patterns, s) {
+			return fmt.Errorf("argument '%s' value '%s' is not allowed", name, s)
+		}
+	}
+
+	return nil
+}
+
+//Authorize checks the command against the policy, the error is the reason of the rejection
+func (p *Policy) Authorize(cmd *core.Command) error {
+	if matchAny(p.Deny, cmd.Command) {
+		return fmt.Errorf("command '%s' is denied", cmd.Command)
+	}
+
+	if len(p.Allow) > 0 && !matchAny(p.Allow, cmd.Command) {
+		return fmt.Errorf("command '%s' is not allowed", cmd.Command)
+	}
+
+	var arguments map[string]interface{}
+	for _, constraint := range p.Arguments {
+		if ok, _ := path.Match(constraint.Command, cmd.Command); !ok {
+			continue
+		}
+
+		if arguments == nil {
+			if cmd.Arguments == nil {
+				return nil
+			}
+
+			if err := json.Unmarshal(*cmd.Arguments, &arguments); err != nil {
+				return fmt.Errorf("arguments of '%s' can't be checked: %s", cmd.Command, err)
+			}
+		}
+
+		//the arguments are loaded with encoding/json, which matches the keys to the struct fields whatever their case
+		for key, value := range arguments {
+			if !strings.EqualFold(key, constraint.Argument) {
+				continue
+			}
+
+			if err := p.checkArgument(key, value, constraint.Values); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package settings
+
+import (
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/naoina/toml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPolicyAuthorize(t *testing.T) {
+	var cfg AppSettings
+	err := toml.Unmarshal([]byte(`
+[sink.main]
+url = "redis://127.0.0.1:6379"
+
+[sink.main.policy]
+allow = ["info.*", "core.*"]
+deny = ["core.reboot"]
+
+[[sink.main.policy.arguments]]
+command = "core.system"
+argument = "name"
+values = ["/bin/ls", "/usr/bin/*"]
+
+[[sink.main.policy.arguments]]
+command = "core.system"
+argument = "args"
+values = ["-*"]
+`), &cfg)
+
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	assert.Empty(t, cfg.Validate())
+
+	policy := cfg.Sink["main"].Policy
+	if !assert.NotNil(t, policy) {
+		t.Fatal()
+	}
+
+	cmd := func(name string, arguments interface{}) *core.Command {
+		return &core.Command{Command: name, Arguments: core.MustArguments(arguments)}
+	}
+
+	assert.Nil(t, policy.Authorize(cmd("info.cpu", nil)))
+	assert.NotNil(t, policy.Authorize(cmd("core.reboot", nil)))
+	assert.NotNil(t, policy.Authorize(cmd("corex.create", nil)))
+
+	assert.Nil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"name": "/usr/bin/id"})))
+	assert.Nil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"name": "/bin/ls", "args": []string{"-l"}})))
+	assert.NotNil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"name": "/bin/sh"})))
+	assert.NotNil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"name": "/bin/ls", "args": []string{"-l", "/"}})))
+
+	//the arguments are loaded whatever the case of their keys, so the constraints must apply to all of them
+	assert.NotNil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"Name": "/bin/sh"})))
+	assert.NotNil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"name": "/bin/ls", "NAME": "/bin/sh"})))
+	assert.Nil(t, policy.Authorize(cmd("core.system", map[string]interface{}{"Name": "/bin/ls", "ARGS": []string{"-l"}})))
+}
@@ -5,7 +5,10 @@ import (
 	"github.com/g8os/core0/base/utils"
 	"github.com/op/go-logging"
 	"net/url"
+	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 var (
@@ -32,6 +35,11 @@ type Logger struct {
 	FlushInt int
 	//Flush batch size (for loggers that needs it)
 	BatchSize int
+	//Job results retention in hours (db logger only), defaults to 7 days
+	ResultRetention int
+
+	//certificates of rediss:// addresses (redis logger only)
+	Security
 }
 
 //Extension cmd config
@@ -45,6 +53,24 @@ type Extension struct {
 
 	Args []string
 
+	//(optional) run the extension as the given user/group (names or ids) instead of root
+	User   string
+	Group  string
+	Groups []string
+	//(optional) umask in octal (ex: "022")
+	Umask string
+	//(optional) cpu and io scheduling priorities
+	Nice        int
+	IONiceClass string `toml:"ionice_class"`
+	IONiceLevel int    `toml:"ionice_level"`
+	//(optional) resource limits by name (nofile, nproc, core, etc...)
+	Rlimits map[string]uint64
+
+	//(optional) run the extension once as a supervised daemon, calls are sent to it over a unix socket
+	Daemon bool
+	//(optional) socket of the daemon, defaults to /var/run/core-<name>.sock
+	Socket string
+
 	key string
 }
 
@@ -52,17 +78,59 @@ func (e *Extension) Key() string {
 	return e.key
 }
 
-//Security certificate path
+//GetSocket gets the socket path of the daemon of the extension with the given name
+func (e *Extension) GetSocket(name string) string {
+	if e.Socket != "" {
+		return e.Socket
+	}
+
+	return fmt.Sprintf("/var/run/core-%s.sock", name)
+}
+
+//Security certificate paths of the tls (rediss://) connections, the files are reloaded when they change on disk
 type Security struct {
 	CertificateAuthority string
 	ClientCertificate    string
 	ClientCertificateKey string
 }
 
+//Files gets the certificate files in the form expected by the redis pools
+func (s *Security) Files() utils.TLSFiles {
+	return utils.TLSFiles{
+		CertificateAuthority: s.CertificateAuthority,
+		Certificate:          s.ClientCertificate,
+		Key:                  s.ClientCertificateKey,
+	}
+}
+
+//Signature verification of the commands received from the sinks
+type Signature struct {
+	//Required rejects the unsigned commands, otherwise only the signed commands are verified
+	Required bool
+	//Window in seconds a signature is valid before and after its timestamp (defaults to 300)
+	Window int
+	//Keys trusted ed25519 public keys (base64) by key id
+	Keys map[string]string
+	//Node identifier of this node, signatures must name it (defaults to the hostname)
+	Node string
+}
+
 //Controller url and certificates
 type SinkConfig struct {
 	URL      string
 	Password string
+	//certificates of rediss:// urls
+	Security
+	//MaxJobs max number of concurrent jobs received from this sink (0 means only the global max_jobs applies)
+	MaxJobs int
+	//Requeue the commands that were received but not answered before a restart, instead of reporting them as
+	//interrupted (redis sinks only)
+	Requeue bool
+	//Policy (optional) restricts the commands received from this sink
+	Policy *Policy
+	//Certificate and CertificateKey of the listener of https sinks
+	Certificate    string
+	CertificateKey string
 }
 
 type Globals map[string]string
@@ -89,21 +157,54 @@ type AppSettings struct {
 
 	Sink map[string]SinkConfig
 
+	Signature Signature
+
 	Extension map[string]Extension
 
 	Logging map[string]Logger
 
+	//Capture writes the full output of all jobs to files, disabled if no directory is set
+	Capture struct {
+		Directory string
+		//MaxSize of a file in bytes before it's rotated
+		MaxSize int64
+		//MaxFiles number of rotated files kept per job stream
+		MaxFiles int
+		//Retention in hours of the output of finished jobs
+		Retention int
+	}
+
 	Stats struct {
 		Interval int
 		Redis    struct {
 			Enabled       bool
 			FlushInterval int
 			Address       string
+			Security
 		}
 	}
 }
 
-var Settings AppSettings
+var (
+	//Settings are the settings core0 was started with, they are not changed by a reload. The settings that follow
+	//the reloads must be read with Current.
+	Settings AppSettings
+
+	//file the settings were loaded from
+	settingsFile string
+
+	//current holds a *AppSettings, swapped as a whole by a reload so readers never see partial settings
+	current atomic.Value
+)
+
+//Current gets the last loaded settings, the returned settings must not be modified
+func Current() *AppSettings {
+	if s, ok := current.Load().(*AppSettings); ok {
+		return s
+	}
+
+	return &Settings
+}
 
 func (s *AppSettings) Validate() []error {
 	if s.Main.LogLevel == "" {
@@ -115,10 +216,23 @@ func (s *AppSettings) Validate() []error {
 		if u, err := url.Parse(con.URL); err != nil {
 			verr := fmt.Errorf("[sink.%s] `url`: %s", name, err)
 			errors = append(errors, verr)
-		} else if !utils.InString([]string{"redis"}, strings.ToLower(u.Scheme)) {
-			verr := fmt.Errorf("[sink.%s] `url` has unknown schema (%s), only redis is allowed atm", name, u.Scheme)
+		} else if !utils.InString([]string{"redis", "rediss", "http", "https"}, strings.ToLower(u.Scheme)) {
+			verr := fmt.Errorf("[sink.%s] `url` has unknown schema (%s), only redis, rediss, http and https are allowed", name, u.Scheme)
+			errors = append(errors, verr)
+		} else if strings.ToLower(u.Scheme) == "https" && (con.Certificate == "" || con.CertificateKey == "") {
+			verr := fmt.Errorf("[sink.%s] https requires `certificate` and `certificate_key`", name)
+			errors = append(errors, verr)
+		} else if strings.HasPrefix(strings.ToLower(u.Scheme), "http") && con.Password == "" {
+			//the http api is not protected otherwise
+			verr := fmt.Errorf("[sink.%s] http and https sinks require a `password`", name)
 			errors = append(errors, verr)
 		}
+
+		if con.Policy != nil {
+			if err := con.Policy.Validate(); err != nil {
+				errors = append(errors, fmt.Errorf("[sink.%s.policy] %s", name, err))
+			}
+		}
 	}
 
 	return errors
@@ -131,5 +245,94 @@ func LoadSettings(filename string) error {
 		return err
 	}
 
+	settingsFile = filename
+	current.Store(&Settings)
 	return nil
 }
+
+/*
+ReloadSettings loads the main settings again from the same file, the current settings are kept if the file is invalid.
+It returns the changed sections that are only applied on restart (see RestartRequired).
+*/
+func ReloadSettings() ([]string, error) {
+	var reloaded AppSettings
+	if err := utils.LoadTomlFile(settingsFile, &reloaded); err != nil {
+		return nil, err
+	}
+
+	if errors := reloaded.Validate(); len(errors) > 0 {
+		return nil, fmt.Errorf("invalid settings: %v", errors)
+	}
+
+	if reloaded.Sink == nil {
+		reloaded.Sink = make(map[string]SinkConfig)
+	}
+
+	restart := RestartRequired(Current(), &reloaded)
+	current.Store(&reloaded)
+
+	return restart, nil
+}
+
+/*
+RestartRequired lists the sections that differ between the old and new settings and are not applied by a reload.
+Sinks and loggers are listed by name (ex: sink.main, logging.console), the sink policies are left out since they
+apply to the commands received after the reload, so are the extensions and the include directory.
+*/
+func RestartRequired(old, new *AppSettings) []string {
+	var changed []string
+
+	sinks := func(s *AppSettings) map[string]SinkConfig {
+		m := make(map[string]SinkConfig)
+		for key, sink := range s.Sink {
+			sink.Policy = nil
+			m[key] = sink
+		}
+		return m
+	}
+
+	oldSinks, newSinks := sinks(old), sinks(new)
+	for key := range union(oldSinks, newSinks) {
+		if !reflect.DeepEqual(oldSinks[key], newSinks[key]) {
+			changed = append(changed, fmt.Sprintf("sink.%s", key))
+		}
+	}
+
+	for key := range union(old.Logging, new.Logging) {
+		if !reflect.DeepEqual(old.Logging[key], new.Logging[key]) {
+			changed = append(changed, fmt.Sprintf("logging.%s", key))
+		}
+	}
+
+	oldMain, newMain := old.Main, new.Main
+	oldMain.Include, newMain.Include = "", ""
+
+	for _, section := range []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"main", oldMain, newMain},
+		{"signature", old.Signature, new.Signature},
+		{"capture", old.Capture, new.Capture},
+		{"stats", old.Stats, new.Stats},
+	} {
+		if !reflect.DeepEqual(section.old, section.new) {
+			changed = append(changed, section.name)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+//union gets the keys of both maps
+func union(maps ...interface{}) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, m := range maps {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			keys[key.String()] = struct{}{}
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,73 @@
+package settings
+
+import (
+	"github.com/naoina/toml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRestartRequired(t *testing.T) {
+	load := func(config string) *AppSettings {
+		var s AppSettings
+		if err := toml.Unmarshal([]byte(config), &s); !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return &s
+	}
+
+	old := load(`
+[main]
+max_jobs = 100
+include = "/etc/g8os/conf"
+
+[sink.main]
+url = "redis://127.0.0.1:6379"
+
+[logging.console]
+type = "console"
+levels = [1, 2]
+`)
+
+	//the include directory, extensions and sink policies are applied by the reload
+	assert.Empty(t, RestartRequired(old, load(`
+[main]
+max_jobs = 100
+include = "/etc/g8os/other"
+
+[sink.main]
+url = "redis://127.0.0.1:6379"
+
+[sink.main.policy]
+allow = ["info.*"]
+
+[extension.ext]
+binary = "/bin/ext"
+
+[logging.console]
+type = "console"
+levels = [1, 2]
+`)))
+
+	assert.Equal(t, []string{"logging.console", "main", "sink.main", "sink.other"}, RestartRequired(old, load(`
+[main]
+max_jobs = 10
+
+[sink.main]
+url = "redis://127.0.0.1:6380"
+
+[sink.other]
+url = "redis://127.0.0.1:6379"
+
+[logging.console]
+type = "console"
+levels = [1, 2, 3]
+`)))
+}
+
+func TestValidateHTTPSinkPassword(t *testing.T) {
+	s := AppSettings{Sink: map[string]SinkConfig{"api": {URL: "http://127.0.0.1:8080"}}}
+	assert.Len(t, s.Validate(), 1)
+
+	s.Sink["api"] = SinkConfig{URL: "http://127.0.0.1:8080", Password: "secret"}
+	assert.Empty(t, s.Validate())
+}
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"fmt"
+	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/utils"
 )
 
@@ -13,6 +14,15 @@ type Startup struct {
 	Name         string
 	Args         map[string]interface{}
 
+	//restart policy of the service (never, on-failure, always)
+	RestartPolicy     string
+	MaxRestart        int
+	RestartBackoff    int
+	RestartMaxBackoff int
+
+	//health check of the service
+	HealthCheck *core.HealthCheck
+
 	key          string
 }
 
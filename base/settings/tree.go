@@ -80,3 +80,52 @@ func (i *IncludedSettings) GetStartupTree() (StartupTree, []error) {
 
 	return tree, errors
 }
+
+//Dependencies gets the keys of all the startup services the given service depends on, directly or indirectly.
+func (i *IncludedSettings) Dependencies(key string) []string {
+	var deps []string
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+
+	for len(queue) > 0 {
+		s, ok := i.Startup[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		for _, a := range s.After {
+			if _, ok := i.Startup[a]; !ok || seen[a] {
+				//not a service (init, net, boot) or already visited
+				continue
+			}
+
+			seen[a] = true
+			deps = append(deps, a)
+			queue = append(queue, a)
+		}
+	}
+
+	sort.Strings(deps)
+	return deps
+}
+
+//Dependents gets the keys of all the startup services that depend on the given service, directly or indirectly.
+func (i *IncludedSettings) Dependents(key string) []string {
+	var deps []string
+	for k := range i.Startup {
+		if k == key {
+			continue
+		}
+
+		for _, d := range i.Dependencies(k) {
+			if d == key {
+				deps = append(deps, k)
+				break
+			}
+		}
+	}
+
+	sort.Strings(deps)
+	return deps
+}
@@ -244,3 +244,15 @@ func TestGetTreeCyclicDependency(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestDependencies(t *testing.T) {
+	assert.Equal(t, []string{"influx", "mongo"}, settings.Dependencies("ovc"))
+	assert.Equal(t, []string{"udev"}, settings.Dependencies("fstab"))
+	assert.Empty(t, settings.Dependencies("sshd"))
+}
+
+func TestDependents(t *testing.T) {
+	assert.Equal(t, []string{"ovc"}, settings.Dependents("mongo"))
+	assert.Equal(t, []string{"fstab"}, settings.Dependents("udev"))
+	assert.Empty(t, settings.Dependents("ovc"))
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultSignatureWindow how long a signature is valid before and after its timestamp
+	DefaultSignatureWindow = 5 * time.Minute
+)
+
+/*
+Verifier verifies the signatures of the commands received from the sinks. A signature is only valid within the window
+around its timestamp, and its nonce is remembered for that long so the same command can't be replayed. The nonces are
+only kept in memory, so signatures made before the verifier was created are refused: they could have been used
+before a restart. A signature is also only valid on the node it names.
+*/
+type Verifier struct {
+	keys     map[string]ed25519.PublicKey
+	required bool
+	window   time.Duration
+	node     string
+	started  int64
+
+	//nonces seen in the window, with the time they can be forgotten
+	nonces map[string]time.Time
+	m      sync.Mutex
+}
+
+//NewVerifier creates a verifier from the signature settings
+func NewVerifier(cfg *settings.Signature) (*Verifier, error) {
+	verifier := &Verifier{
+		keys:     make(map[string]ed25519.PublicKey),
+		required: cfg.Required,
+		window:   time.Duration(cfg.Window) * time.Second,
+		node:     cfg.Node,
+		started:  time.Now().Unix(),
+		nonces:   make(map[string]time.Time),
+	}
+
+	if verifier.window <= 0 {
+		verifier.window = DefaultSignatureWindow
+	}
+
+	if verifier.node == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the node identifier: %s", err)
+		}
+
+		verifier.node = hostname
+	}
+
+	for id, value := range cfg.Keys {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s': %s", id, err)
+		}
+
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key '%s': expected %d bytes", id, ed25519.PublicKeySize)
+		}
+
+		verifier.keys[id] = ed25519.PublicKey(key)
+	}
+
+	if verifier.required && len(verifier.keys) == 0 {
+		return nil, fmt.Errorf("signatures are required but no keys are trusted")
+	}
+
+	return verifier, nil
+}
+
+//nonce records the nonce until the signature expires, it fails if it was already seen
+func (v *Verifier) nonce(id string, now, expires time.Time) error {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	for nonce, expires := range v.nonces {
+		if now.After(expires) {
+			delete(v.nonces, nonce)
+		}
+	}
+
+	if _, ok := v.nonces[id]; ok {
+		return fmt.Errorf("replayed signature")
+	}
+
+	v.nonces[id] = expires
+	return nil
+}
+
+//Verify checks the signature of the command, the error is the reason of the rejection
+func (v *Verifier) Verify(cmd *core.Command) error {
+	signature := cmd.Signature
+	if signature == nil {
+		if v.required {
+			return fmt.Errorf("command is not signed")
+		}
+
+		return nil
+	}
+
+	key, ok := v.keys[signature.KeyID]
+	if !ok {
+		return fmt.Errorf("unknown key '%s'", signature.KeyID)
+	}
+
+	if signature.Node != v.node {
+		return fmt.Errorf("signature is for node '%s'", signature.Node)
+	}
+
+	now := time.Now()
+	signed := time.Unix(signature.Timestamp, 0)
+	if signed.Before(now.Add(-v.window)) || signed.After(now.Add(v.window)) {
+		return fmt.Errorf("signature timestamp is out of the replay window")
+	}
+
+	if signature.Timestamp < v.started {
+		return fmt.Errorf("signature was made before core started")
+	}
+
+	if signature.Nonce == "" {
+		return fmt.Errorf("signature has no nonce")
+	}
+
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %s", err)
+	}
+
+	if cmd.Payload() == nil {
+		return fmt.Errorf("command payload is not available")
+	}
+
+	message, err := core.Canonical(cmd.Payload())
+	if err != nil {
+		return fmt.Errorf("invalid command: %s", err)
+	}
+
+	if !ed25519.Verify(key, message, value) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	//the nonce is only recorded for valid signatures, so invalid commands can't burn nonces
+	return v.nonce(signature.KeyID+":"+signature.Nonce, now, signed.Add(v.window))
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	verifier, err := NewVerifier(&settings.Signature{
+		Required: true,
+		Keys:     map[string]string{"ops": base64.StdEncoding.EncodeToString(public)},
+		Node:     "node-1",
+	})
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+
+	sign := func(node, nonce string, timestamp int64, tamper bool) *core.Command {
+		payload := fmt.Sprintf(`{"id": "job", "command": "core.ping", "arguments": {"a": 1.0},
+			"signature": {"key_id": "ops", "node": "%s", "timestamp": %d, "nonce": "%s"}}`, node, timestamp, nonce)
+
+		message, err := core.Canonical([]byte(payload))
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+
+		assert.Equal(t, fmt.Sprintf(`{"arguments":{"a":1.0},"command":"core.ping","id":"job",`+
+			`"signature":{"key_id":"ops","node":"%s","nonce":"%s","timestamp":%d}}`, node, nonce, timestamp), string(message))
+
+		value := base64.StdEncoding.EncodeToString(ed25519.Sign(private, message))
+		if tamper {
+			payload = payload[:len(payload)-2] + fmt.Sprintf(`, "value": "%s"}, "tags": "x"}`, value)
+		} else {
+			payload = payload[:len(payload)-2] + fmt.Sprintf(`, "value": "%s"}}`, value)
+		}
+
+		cmd, err := core.LoadCmd([]byte(payload))
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+
+		return cmd
+	}
+
+	now := time.Now().Unix()
+	assert.Nil(t, verifier.Verify(sign("node-1", "1", now, false)))
+	assert.NotNil(t, verifier.Verify(sign("node-1", "1", now, false)), "replayed nonce")
+	assert.NotNil(t, verifier.Verify(sign("node-1", "2", now, true)), "tampered command")
+	assert.NotNil(t, verifier.Verify(sign("node-1", "3", now-3600, false)), "expired timestamp")
+	assert.NotNil(t, verifier.Verify(sign("node-2", "4", now, false)), "other node")
+	//within the window, but its nonce could have been used before a restart
+	assert.NotNil(t, verifier.Verify(sign("node-1", "5", now-60, false)), "signed before start")
+	assert.NotNil(t, verifier.Verify(&core.Command{ID: "job", Command: "core.ping"}), "unsigned command")
+}
@@ -1,8 +1,11 @@
 package core
 
 import (
+	"fmt"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"github.com/op/go-logging"
 	"time"
 )
 
@@ -10,6 +13,11 @@ const (
 	ReconnectSleepTime = 10 * time.Second
 )
 
+var (
+	//audit logs the commands rejected by the sinks policies
+	audit = logging.MustGetLogger("audit")
+)
+
 type Sink interface {
 	Run()
 }
@@ -19,10 +27,16 @@ type SinkClient interface {
 	Respond(result *core.JobResult) error
 }
 
+//Acknowledger is implemented by the sink clients that keep the received commands until they are answered
+type Acknowledger interface {
+	Ack(command *core.Command) error
+}
+
 type sinkImpl struct {
-	key    string
-	mgr    *pm.PM
-	client SinkClient
+	key      string
+	mgr      *pm.PM
+	client   SinkClient
+	verifier *Verifier
 }
 
 func getKeys(m map[string]SinkClient) []string {
@@ -34,11 +48,13 @@ func getKeys(m map[string]SinkClient) []string {
 	return keys
 }
 
-func NewSink(key string, mgr *pm.PM, client SinkClient) Sink {
+//NewSink creates a sink that feeds the manager with the commands of the client, verifier is optional
+func NewSink(key string, mgr *pm.PM, client SinkClient, verifier *Verifier) Sink {
 	poll := &sinkImpl{
-		key:    key,
-		mgr:    mgr,
-		client: client,
+		key:      key,
+		mgr:      mgr,
+		client:   client,
+		verifier: verifier,
 	}
 
 	return poll
@@ -47,7 +63,65 @@ func NewSink(key string, mgr *pm.PM, client SinkClient) Sink {
 func (poll *sinkImpl) handler(cmd *core.Command, result *core.JobResult) {
 	if err := poll.client.Respond(result); err != nil {
 		log.Errorf("Failed to respond to command %s: %s", cmd, err)
+		return
 	}
+
+	poll.ack(cmd)
+}
+
+//ack acknowledges the delivery of the command once it's answered, so it's not delivered again
+func (poll *sinkImpl) ack(cmd *core.Command) {
+	acknowledger, ok := poll.client.(Acknowledger)
+	if !ok {
+		return
+	}
+
+	if err := acknowledger.Ack(cmd); err != nil {
+		log.Errorf("Failed to acknowledge command %s: %s", cmd, err)
+	}
+}
+
+//reject answers the command with an UNAUTHORIZED result, the command never reaches the process manager
+func (poll *sinkImpl) reject(cmd *core.Command, reason error) {
+	audit.Warningf("Rejected command %s (tags: '%s') from sink '%s': %s", cmd, cmd.Tags, poll.key, reason)
+
+	result := &core.JobResult{
+		ID:       cmd.ID,
+		Command:  cmd.Command,
+		Tags:     cmd.Tags,
+		State:    core.StateUnauthorized,
+		Critical: reason.Error(),
+	}
+
+	if err := poll.client.Respond(result); err != nil {
+		log.Errorf("Failed to respond to command %s: %s", cmd, err)
+		return
+	}
+
+	poll.ack(cmd)
+}
+
+/*
+authorize verifies the signature of the command, and checks it against the policy of the sink. The policy is read on
+each command so it follows the reloads. Commands can't use the job ids reserved for the extensions daemons.
+*/
+func (poll *sinkImpl) authorize(cmd *core.Command) error {
+	if pm.IsReservedID(cmd.ID) {
+		return fmt.Errorf("job id '%s' is reserved", cmd.ID)
+	}
+
+	if poll.verifier != nil {
+		if err := poll.verifier.Verify(cmd); err != nil {
+			return err
+		}
+	}
+
+	cfg, ok := settings.Current().Sink[poll.key]
+	if !ok || cfg.Policy == nil {
+		return nil
+	}
+
+	return cfg.Policy.Authorize(cmd)
 }
 
 func (poll *sinkImpl) run() {
@@ -70,6 +144,11 @@ func (poll *sinkImpl) run() {
 
 		command.Route = core.Route(poll.key)
 
+		if err := poll.authorize(&command); err != nil {
+			poll.reject(&command, err)
+			continue
+		}
+
 		log.Infof("Starting command %s", &command)
 
 		if command.Queue == "" {
@@ -85,11 +164,12 @@ func (poll *sinkImpl) Run() {
 }
 
 /*
-StartSinks starts the long polling routines and feed the manager with received commands
+StartSinks starts the long polling routines and feed the manager with received commands, if verifier is set the
+signatures of the commands are verified.
 */
-func StartSinks(mgr *pm.PM, sinks map[string]SinkClient) {
+func StartSinks(mgr *pm.PM, sinks map[string]SinkClient, verifier *Verifier) {
 	for key, sinkCl := range sinks {
-		poll := NewSink(key, mgr, sinkCl)
+		poll := NewSink(key, mgr, sinkCl, verifier)
 		poll.Run()
 	}
 }
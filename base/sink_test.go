@@ -0,0 +1,82 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//result waits for the result of the given job to be pushed
+func result(t *testing.T, r *fakeRedis, id string) *core.JobResult {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if results := r.list("result:" + id); len(results) > 0 {
+			var result core.JobResult
+			if !assert.Nil(t, json.Unmarshal(results[0], &result)) {
+				t.FailNow()
+			}
+			return &result
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no result for job %s", id)
+	return nil
+}
+
+func TestSinkReservedID(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	client := newTestSink(t, r, false)
+	NewSink("test", pm.InitProcessManager(10), client, nil).Run()
+
+	//a client job must not take the id of an extension daemon
+	r.push(client.DefaultQueue(), command(pm.DaemonJobID("ext")))
+	assert.Equal(t, core.StateUnauthorized, result(t, r, pm.DaemonJobID("ext")).State)
+}
+
+func TestSinkCoreXUnsigned(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	//like coreX, which answers to the reply queue of core0 and verifies the commands for its container id
+	client, err := NewSinkClient(&settings.SinkConfig{URL: r.URL()}, "1", "corex:results")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	public, _, _ := ed25519.GenerateKey(nil)
+	verifier, err := NewVerifier(&settings.Signature{
+		Required: true,
+		Keys:     map[string]string{"ops": base64.StdEncoding.EncodeToString(public)},
+		Node:     "1",
+	})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	NewSink("main", pm.InitProcessManager(10), client, verifier).Run()
+
+	//another container can push to the queue of the container over the shared redis socket
+	r.push(client.(*sinkClient).DefaultQueue(), command("unsigned"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.list("corex:results")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	results := r.list("corex:results")
+	if assert.Len(t, results, 1) {
+		var result core.JobResult
+		assert.Nil(t, json.Unmarshal(results[0], &result))
+		assert.Equal(t, "unsigned", result.ID)
+		assert.Equal(t, core.StateUnauthorized, result.State)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/g8os/core0/base/utils"
 	"github.com/garyburd/redigo/redis"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -24,11 +26,20 @@ type sinkClient struct {
 	id    string
 
 	responseQueue string
+	requeue       bool
+	recovered     bool
+	//legacy is set if the redis server is older than 6.2, which has no BLMOVE
+	legacy bool
+
+	//payloads of the received commands that were not answered yet, by delivery. Different deliveries can have the
+	//same command id, so the pointer of the received command is used as key.
+	pending map[*core.Command][]byte
+	m       sync.Mutex
 }
 
 /*
 NewSinkClient gets a new sink connection with the given identity. Identity is used by the sink client to
-introduce itself to the sink terminal.
+introduce itself to the sink terminal. http and https urls start an http sink instead (see NewHTTPSinkClient).
 */
 func NewSinkClient(cfg *settings.SinkConfig, id string, responseQueue ...string) (SinkClient, error) {
 	u, err := url.Parse(cfg.URL)
@@ -36,23 +47,26 @@ func NewSinkClient(cfg *settings.SinkConfig, id string, responseQueue ...string)
 		return nil, err
 	}
 
-	if u.Scheme != "redis" {
-		return nil, fmt.Errorf("expected url of format redis://<host>:<port> or redis:///unix.socket")
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return NewHTTPSinkClient(cfg)
+	}
+
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("expected url of format redis://<host>:<port>, rediss://<host>:<port> or redis:///unix.socket")
 	}
 
-	network := "tcp"
-	address := u.Host
-	if address == "" {
-		network = "unix"
-		address = u.Path
+	if _, _, _, err := utils.ParseRedisAddress(cfg.URL); err != nil {
+		return nil, err
 	}
 
-	pool := utils.NewRedisPool(network, address, cfg.Password)
+	pool := utils.NewRedisPoolAddress(cfg.URL, cfg.Password, cfg.Security.Files())
 
 	client := &sinkClient{
-		id:    id,
-		url:   strings.TrimRight(cfg.URL, "/"),
-		redis: pool,
+		id:      id,
+		url:     strings.TrimRight(cfg.URL, "/"),
+		redis:   pool,
+		requeue: cfg.Requeue,
+		pending: make(map[*core.Command][]byte),
 	}
 
 	if len(responseQueue) == 1 {
@@ -74,16 +88,174 @@ func (client *sinkClient) DefaultQueue() string {
 	)
 }
 
+//ProcessingQueue holds the commands that were popped from the default queue and not answered yet
+func (client *sinkClient) ProcessingQueue() string {
+	return fmt.Sprintf("%s:processing", client.DefaultQueue())
+}
+
+/*
+checkVersion checks if the redis server supports BLMOVE (6.2 or later), older servers fall back to BRPOPLPUSH
+which takes the newest command first.
+*/
+func (cl *sinkClient) checkVersion(db redis.Conn) error {
+	info, err := redis.String(db.Do("INFO", "server"))
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "redis_version:") {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(line, "redis_version:"), ".", 3)
+		major, _ := strconv.Atoi(parts[0])
+		minor := 0
+		if len(parts) > 1 {
+			minor, _ = strconv.Atoi(parts[1])
+		}
+
+		cl.legacy = major < 6 || (major == 6 && minor < 2)
+		if cl.legacy {
+			log.Warningf("Redis of %s is older than 6.2, commands will be taken newest first", cl)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown redis version")
+}
+
+/*
+recoverProcessing handles the commands left in the processing list by a previous run, they are either pushed back to
+the queue or answered with an INTERRUPTED result.
+*/
+func (cl *sinkClient) recoverProcessing(db redis.Conn) error {
+	if cl.requeue {
+		//the processing list holds the newest command first, moving them one by one to the head of the queue puts
+		//the oldest one first. Each move is atomic, so a command is never lost if we crash in between.
+		count := 0
+		for {
+			var err error
+			if cl.legacy {
+				//the commands are taken from the tail, so they run after the queued ones
+				_, err = redis.Bytes(db.Do("RPOPLPUSH", cl.ProcessingQueue(), cl.DefaultQueue()))
+			} else {
+				_, err = redis.Bytes(db.Do("LMOVE", cl.ProcessingQueue(), cl.DefaultQueue(), "LEFT", "LEFT"))
+			}
+
+			if err == redis.ErrNil {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		if count > 0 {
+			log.Warningf("Requeued %d unacknowledged commands from %s", count, cl)
+		}
+
+		return nil
+	}
+
+	payloads, err := redis.ByteSlices(db.Do("LRANGE", cl.ProcessingQueue(), 0, -1))
+	if err != nil {
+		return err
+	}
+
+	for _, payload := range payloads {
+		var command core.Command
+		if err := json.Unmarshal(payload, &command); err != nil || command.ID == "" {
+			continue
+		}
+
+		log.Warningf("Command %s was interrupted", &command)
+		result := &core.JobResult{
+			ID:       command.ID,
+			Command:  command.Command,
+			Tags:     command.Tags,
+			State:    core.StateInterrupted,
+			Critical: "core was restarted before the command completed",
+		}
+
+		if err := cl.Respond(result); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Do("DEL", cl.ProcessingQueue())
+	return err
+}
+
 func (cl *sinkClient) GetNext(command *core.Command) error {
 	db := cl.redis.Get()
 	defer db.Close()
 
-	payload, err := redis.ByteSlices(db.Do("BLPOP", cl.DefaultQueue(), 0))
+	if !cl.recovered {
+		if err := cl.checkVersion(db); err != nil {
+			return err
+		}
+
+		if err := cl.recoverProcessing(db); err != nil {
+			return err
+		}
+
+		cl.recovered = true
+	}
+
+	//commands are pushed with RPUSH, so the oldest one is popped from the left. The command is only removed from
+	//the processing list once it's answered (see Ack).
+	var payload []byte
+	var err error
+	if cl.legacy {
+		payload, err = redis.Bytes(db.Do("BRPOPLPUSH", cl.DefaultQueue(), cl.ProcessingQueue(), 0))
+	} else {
+		payload, err = redis.Bytes(db.Do("BLMOVE", cl.DefaultQueue(), cl.ProcessingQueue(), "LEFT", "LEFT", 0))
+	}
+
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(payload[1], command)
+	if err := json.Unmarshal(payload, command); err != nil {
+		db.Do("LREM", cl.ProcessingQueue(), 1, payload)
+		return err
+	}
+
+	if command.ID == "" {
+		//no result can be pushed for it, so it can't be acknowledged later
+		_, err := db.Do("LREM", cl.ProcessingQueue(), 1, payload)
+		return err
+	}
+
+	cl.m.Lock()
+	cl.pending[command] = payload
+	cl.m.Unlock()
+
+	return nil
+}
+
+//Ack removes the received command from the processing list, once its result is pushed
+func (cl *sinkClient) Ack(command *core.Command) error {
+	cl.m.Lock()
+	payload, ok := cl.pending[command]
+	if !ok {
+		//already acknowledged (recurring commands) or not received from this sink
+		cl.m.Unlock()
+		return nil
+	}
+
+	delete(cl.pending, command)
+	cl.m.Unlock()
+
+	db := cl.redis.Get()
+	defer db.Close()
+
+	_, err := db.Do("LREM", cl.ProcessingQueue(), 1, payload)
+	return err
 }
 
 func (cl *sinkClient) Respond(result *core.JobResult) error {
@@ -109,9 +281,6 @@ func (cl *sinkClient) Respond(result *core.JobResult) error {
 	if _, err := db.Do("RPUSH", queue, payload); err != nil {
 		return err
 	}
-	if _, err := db.Do("EXPIRE", queue, ReturnExpire); err != nil {
-		return err
-	}
-
-	return nil
+	_, err = db.Do("EXPIRE", queue, ReturnExpire)
+	return err
 }
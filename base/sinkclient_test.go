@@ -0,0 +1,298 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//fakeRedis implements the list commands used by the sink client, enough to test the delivery without a redis server
+type fakeRedis struct {
+	version  string
+	lists    map[string][][]byte
+	m        sync.Mutex
+	pushed   *sync.Cond
+	listener net.Listener
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	r := &fakeRedis{
+		version:  "6.2.0",
+		lists:    make(map[string][][]byte),
+		listener: listener,
+	}
+	r.pushed = sync.NewCond(&r.m)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+
+	return r
+}
+
+func (r *fakeRedis) URL() string {
+	return fmt.Sprintf("redis://%s", r.listener.Addr())
+}
+
+func (r *fakeRedis) Close() {
+	r.listener.Close()
+}
+
+func (r *fakeRedis) push(key string, values ...[]byte) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.lists[key] = append(r.lists[key], values...)
+	r.pushed.Broadcast()
+}
+
+func (r *fakeRedis) list(key string) [][]byte {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([][]byte(nil), r.lists[key]...)
+}
+
+func readCommand(reader *bufio.Reader) ([][]byte, error) {
+	var count int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+		return nil, err
+	}
+
+	args := make([][]byte, count)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+
+		args[i] = make([]byte, size+2)
+		if _, err := io.ReadFull(reader, args[i]); err != nil {
+			return nil, err
+		}
+		args[i] = args[i][:size]
+	}
+
+	return args, nil
+}
+
+func writeBulk(w io.Writer, value []byte) {
+	if value == nil {
+		fmt.Fprint(w, "$-1\r\n")
+		return
+	}
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value)
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		r.do(conn, string(bytes.ToUpper(args[0])), args[1:])
+	}
+}
+
+//move pops the head (or the tail) of src and pushes it to the head of dst, it must be called with the lock held
+func (r *fakeRedis) move(src, dst string, tail bool) []byte {
+	values := r.lists[src]
+	if len(values) == 0 {
+		return nil
+	}
+
+	value := values[0]
+	if tail {
+		value = values[len(values)-1]
+		r.lists[src] = values[:len(values)-1]
+	} else {
+		r.lists[src] = values[1:]
+	}
+
+	r.lists[dst] = append([][]byte{value}, r.lists[dst]...)
+	return value
+}
+
+func (r *fakeRedis) do(w io.Writer, cmd string, args [][]byte) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	switch cmd {
+	case "RPUSH":
+		r.lists[string(args[0])] = append(r.lists[string(args[0])], args[1:]...)
+		r.pushed.Broadcast()
+		fmt.Fprintf(w, ":%d\r\n", len(r.lists[string(args[0])]))
+	case "LMOVE", "BLMOVE", "RPOPLPUSH", "BRPOPLPUSH":
+		//only LEFT LEFT is implemented for LMOVE, the blocking commands wait forever
+		for cmd[0] == 'B' && len(r.lists[string(args[0])]) == 0 {
+			r.pushed.Wait()
+		}
+		writeBulk(w, r.move(string(args[0]), string(args[1]), strings.HasSuffix(cmd, "RPOPLPUSH")))
+	case "LRANGE":
+		values := r.lists[string(args[0])]
+		fmt.Fprintf(w, "*%d\r\n", len(values))
+		for _, value := range values {
+			writeBulk(w, value)
+		}
+	case "LREM":
+		values := r.lists[string(args[0])]
+		removed := 0
+		for i, value := range values {
+			if bytes.Equal(value, args[2]) {
+				r.lists[string(args[0])] = append(values[:i:i], values[i+1:]...)
+				removed = 1
+				break
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", removed)
+	case "DEL":
+		delete(r.lists, string(args[0]))
+		fmt.Fprint(w, ":1\r\n")
+	case "EXPIRE":
+		fmt.Fprint(w, ":1\r\n")
+	case "INFO":
+		writeBulk(w, []byte(fmt.Sprintf("# Server\r\nredis_version:%s\r\n", r.version)))
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+func command(id string) []byte {
+	data, _ := json.Marshal(map[string]string{"id": id, "command": "core.ping"})
+	return data
+}
+
+func newTestSink(t *testing.T, r *fakeRedis, requeue bool) *sinkClient {
+	client, err := NewSinkClient(&settings.SinkConfig{URL: r.URL(), Requeue: requeue}, "test")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return client.(*sinkClient)
+}
+
+func receive(t *testing.T, client *sinkClient) *core.Command {
+	var cmd core.Command
+	if !assert.Nil(t, client.GetNext(&cmd)) {
+		t.FailNow()
+	}
+
+	return &cmd
+}
+
+func next(t *testing.T, client *sinkClient) string {
+	return receive(t, client).ID
+}
+
+func TestSinkClientAck(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	client := newTestSink(t, r, false)
+	r.push(client.DefaultQueue(), command("a"), command("b"), command("c"))
+
+	//commands are pushed with RPUSH, they must run in the same order
+	a := receive(t, client)
+	assert.Equal(t, "a", a.ID)
+	assert.Equal(t, "b", next(t, client))
+	assert.Len(t, r.list(client.ProcessingQueue()), 2)
+
+	assert.Nil(t, client.Respond(&core.JobResult{ID: "a", State: core.StateSuccess}))
+	assert.Len(t, r.list("result:a"), 1)
+	assert.Nil(t, client.Ack(a))
+	assert.Equal(t, [][]byte{command("b")}, r.list(client.ProcessingQueue()))
+
+	assert.Equal(t, "c", next(t, client))
+}
+
+func TestSinkClientAckDelivery(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	client := newTestSink(t, r, false)
+	running := []byte(`{"id": "a", "command": "core.ping", "tags": "running"}`)
+	duplicate := []byte(`{"id": "a", "command": "core.ping", "tags": "duplicate"}`)
+	r.push(client.DefaultQueue(), running, duplicate)
+
+	receive(t, client)
+	cmd := receive(t, client)
+
+	//the result of the duplicate only acknowledges its own delivery
+	assert.Nil(t, client.Ack(cmd))
+	assert.Equal(t, [][]byte{running}, r.list(client.ProcessingQueue()))
+
+	//a command is acknowledged once (recurring commands)
+	assert.Nil(t, client.Ack(cmd))
+	assert.Equal(t, [][]byte{running}, r.list(client.ProcessingQueue()))
+}
+
+func TestSinkClientLegacy(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	r.m.Lock()
+	r.version = "3.0.6"
+	r.m.Unlock()
+
+	//redis older than 6.2 has no BLMOVE, the newest command is taken first
+	client := newTestSink(t, r, false)
+	r.push(client.DefaultQueue(), command("a"), command("b"))
+	b := receive(t, client)
+	assert.Equal(t, "b", b.ID)
+	assert.Equal(t, [][]byte{command("b")}, r.list(client.ProcessingQueue()))
+
+	assert.Nil(t, client.Ack(b))
+	assert.Empty(t, r.list(client.ProcessingQueue()))
+	assert.Equal(t, "a", next(t, client))
+}
+
+func TestSinkClientRecovery(t *testing.T) {
+	r := newFakeRedis(t)
+	defer r.Close()
+
+	previous := newTestSink(t, r, false)
+	r.push(previous.DefaultQueue(), command("a"), command("b"), command("c"))
+	assert.Equal(t, "a", next(t, previous))
+	assert.Equal(t, "b", next(t, previous))
+
+	//the commands left unanswered run again first, in the order they were received
+	client := newTestSink(t, r, true)
+	assert.Equal(t, "a", next(t, client))
+	assert.Equal(t, "b", next(t, client))
+	assert.Equal(t, "c", next(t, client))
+
+	//without requeue they are answered as interrupted
+	client = newTestSink(t, r, false)
+	r.push(client.DefaultQueue(), command("d"))
+	assert.Equal(t, "d", next(t, client))
+
+	results := r.list("result:a")
+	if assert.Len(t, results, 1) {
+		var result core.JobResult
+		assert.Nil(t, json.Unmarshal(results[0], &result))
+		assert.Equal(t, core.StateInterrupted, result.State)
+	}
+
+	assert.Equal(t, [][]byte{command("d")}, r.list(client.ProcessingQueue()))
+}
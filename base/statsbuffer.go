@@ -26,8 +26,10 @@ type redisStatsBuffer struct {
 	pool   *redis.Pool
 }
 
-func NewRedisStatsBuffer(address string, password string, capacity int, flushInt time.Duration) StatsFlusher {
-	pool := utils.NewRedisPool("tcp", address, password)
+//NewRedisStatsBuffer creates a stats buffer flushed to redis, address is a host:port or a redis url (rediss:// urls
+//use the given certificate files)
+func NewRedisStatsBuffer(address string, password string, files utils.TLSFiles, capacity int, flushInt time.Duration) StatsFlusher {
+	pool := utils.NewRedisPoolAddress(address, password, files)
 
 	redisBuffer := &redisStatsBuffer{
 		pool: pool,
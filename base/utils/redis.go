@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"crypto/tls"
+	"fmt"
 	"github.com/garyburd/redigo/redis"
+	"net"
+	"net/url"
+	"strings"
 )
 
-func NewRedisPool(network string, address string, password string) *redis.Pool {
+func newRedisPool(dial func() (redis.Conn, error), password string) *redis.Pool {
 	return &redis.Pool{
 		MaxIdle:   80,
 		MaxActive: 12000,
 		Dial: func() (redis.Conn, error) {
-			c, err := redis.Dial(network, address)
+			c, err := dial()
 
 			if err != nil {
 				return nil, err
@@ -26,3 +31,88 @@ func NewRedisPool(network string, address string, password string) *redis.Pool {
 		},
 	}
 }
+
+func NewRedisPool(network string, address string, password string) *redis.Pool {
+	return newRedisPool(func() (redis.Conn, error) {
+		return redis.Dial(network, address)
+	}, password)
+}
+
+/*
+NewRedisTLSPool creates a pool of tls connections to the given tcp address. The certificates are read again on new
+connections if they changed on disk.
+*/
+func NewRedisTLSPool(address string, password string, files TLSFiles) *redis.Pool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	loader := NewTLSLoader(files, host)
+	return newRedisPool(func() (redis.Conn, error) {
+		config, err := loader.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		return redis.Dial("tcp", address, redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+			return tls.Dial(network, addr, config)
+		}))
+	}, password)
+}
+
+/*
+ParseRedisAddress splits a redis address into network and address. The address is either a url (redis://host:port,
+redis:///path/to/socket or rediss://host:port for tls) or a bare host:port or socket path. The tls flag is set for
+rediss urls.
+*/
+func ParseRedisAddress(address string) (network string, addr string, secure bool, err error) {
+	if !strings.Contains(address, "://") {
+		if strings.Index(address, ":") > 0 {
+			return "tcp", address, false, nil
+		}
+
+		return "unix", address, false, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	switch u.Scheme {
+	case "redis":
+		if u.Host == "" {
+			return "unix", u.Path, false, nil
+		}
+
+		return "tcp", u.Host, false, nil
+	case "rediss":
+		if u.Host == "" {
+			return "", "", false, fmt.Errorf("rediss url requires a host")
+		}
+
+		return "tcp", u.Host, true, nil
+	}
+
+	return "", "", false, fmt.Errorf("expected url of format redis://<host>:<port>, rediss://<host>:<port> or redis:///unix.socket")
+}
+
+/*
+NewRedisPoolAddress creates a pool for an address as accepted by ParseRedisAddress, files are the certificates used
+by rediss urls. An invalid address is reported on every connection attempt.
+*/
+func NewRedisPoolAddress(address string, password string, files TLSFiles) *redis.Pool {
+	network, addr, secure, err := ParseRedisAddress(address)
+	if err != nil {
+		return newRedisPool(func() (redis.Conn, error) {
+			return nil, err
+		}, password)
+	}
+
+	if secure {
+		return NewRedisTLSPool(addr, password, files)
+	}
+
+	return NewRedisPool(network, addr, password)
+}
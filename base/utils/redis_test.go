@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestParseRedisAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		network string
+		addr    string
+		secure  bool
+	}{
+		{"127.0.0.1:6379", "tcp", "127.0.0.1:6379", false},
+		{"/var/run/redis.socket", "unix", "/var/run/redis.socket", false},
+		{"redis://127.0.0.1:6379", "tcp", "127.0.0.1:6379", false},
+		{"redis:///var/run/redis.socket", "unix", "/var/run/redis.socket", false},
+		{"rediss://redis.example.com:6380", "tcp", "redis.example.com:6380", true},
+	}
+
+	for _, c := range cases {
+		network, addr, secure, err := ParseRedisAddress(c.address)
+		if assert.Nil(t, err, c.address) {
+			assert.Equal(t, c.network, network, c.address)
+			assert.Equal(t, c.addr, addr, c.address)
+			assert.Equal(t, c.secure, secure, c.address)
+		}
+	}
+
+	_, _, _, err := ParseRedisAddress("rediss:///var/run/redis.socket")
+	assert.NotNil(t, err)
+	_, _, _, err = ParseRedisAddress("http://127.0.0.1:6379")
+	assert.NotNil(t, err)
+}
+
+//writeCertificate writes a self signed certificate for 127.0.0.1 and its key
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	ioutil.WriteFile(certFile, certPEM, 0600)
+	ioutil.WriteFile(keyFile, keyPEM, 0600)
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return certificate
+}
+
+func TestRedisTLSPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := path.Join(dir, "cert.pem"), path.Join(dir, "key.pem")
+	server := writeCertificate(t, certFile, keyFile, 1)
+
+	clients := x509.NewCertPool()
+	clients.AddCert(mustParse(t, server))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clients,
+	})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				//PING is sent as a resp array of 1 bulk string: *1 $4 PING
+				for i := 0; i < 3; i++ {
+					if _, err := reader.ReadString('\n'); err != nil {
+						return
+					}
+				}
+				conn.Write([]byte("+PONG\r\n"))
+			}(conn)
+		}
+	}()
+
+	//the server certificate is used as ca and client certificate
+	pool := NewRedisPoolAddress("rediss://"+listener.Addr().String(), "", TLSFiles{
+		CertificateAuthority: certFile,
+		Certificate:          certFile,
+		Key:                  keyFile,
+	})
+
+	conn := pool.Get()
+	reply, err := conn.Do("PING")
+	conn.Close()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "PONG", reply)
+	}
+
+	//a new certificate on disk is picked up by the next connections
+	loader := NewTLSLoader(TLSFiles{Certificate: certFile, Key: keyFile}, "127.0.0.1")
+	before, err := loader.Config()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	writeCertificate(t, certFile, keyFile, 2)
+	after, err := loader.Config()
+	if assert.Nil(t, err) {
+		assert.NotEqual(t, before.Certificates[0].Certificate[0], after.Certificates[0].Certificate[0])
+	}
+}
+
+func mustParse(t *testing.T, certificate tls.Certificate) *x509.Certificate {
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return cert
+}
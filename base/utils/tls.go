@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+//TLSFiles are the paths of the certificate authority and the client certificate of a tls connection, all optional
+type TLSFiles struct {
+	CertificateAuthority string
+	Certificate          string
+	Key                  string
+}
+
+/*
+TLSLoader builds the tls config of a connection from the certificate files. The files are read again whenever one of
+them changes on disk, so certificates can be rotated without a restart.
+*/
+type TLSLoader struct {
+	files      TLSFiles
+	serverName string
+
+	stamp  string
+	config *tls.Config
+	m      sync.Mutex
+}
+
+//NewTLSLoader creates a loader for connections to the given server name
+func NewTLSLoader(files TLSFiles, serverName string) *TLSLoader {
+	return &TLSLoader{
+		files:      files,
+		serverName: serverName,
+	}
+}
+
+//stampFiles identifies the current version of the files
+func (l *TLSLoader) stampFiles() string {
+	stamp := ""
+	for _, name := range []string{l.files.CertificateAuthority, l.files.Certificate, l.files.Key} {
+		if name == "" {
+			continue
+		}
+
+		if info, err := os.Stat(name); err == nil {
+			stamp += fmt.Sprintf("%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+
+	return stamp
+}
+
+func (l *TLSLoader) load() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: l.serverName,
+	}
+
+	if l.files.CertificateAuthority != "" {
+		data, err := ioutil.ReadFile(l.files.CertificateAuthority)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in '%s'", l.files.CertificateAuthority)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if l.files.Certificate != "" || l.files.Key != "" {
+		certificate, err := tls.LoadX509KeyPair(l.files.Certificate, l.files.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+//Config gets the tls config, the last valid config is kept if the files can't be loaded (while they are rewritten)
+func (l *TLSLoader) Config() (*tls.Config, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	stamp := l.stampFiles()
+	if l.config != nil && stamp == l.stamp {
+		return l.config, nil
+	}
+
+	config, err := l.load()
+	if err != nil {
+		if l.config != nil {
+			log.Errorf("Failed to reload certificates, keeping the previous ones: %s", err)
+			return l.config, nil
+		}
+
+		return nil, err
+	}
+
+	l.config = config
+	l.stamp = stamp
+	return config, nil
+}
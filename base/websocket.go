@@ -0,0 +1,193 @@
+package core
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	websocketText  = 0x1
+	websocketClose = 0x8
+	websocketPing  = 0x9
+	websocketPong  = 0xA
+
+	//max size of a frame sent by the client, clients only send control frames to the job streams
+	websocketMaxPayload = 64 * 1024
+)
+
+/*
+websocketConn is a minimal server side websocket (RFC 6455) connection, it only writes unfragmented frames and
+answers the client control frames.
+*/
+type websocketConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	m    sync.Mutex
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+//upgradeWebsocket completes the websocket handshake and takes over the http connection
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerHasToken(r.Header, "Upgrade", "websocket") || !headerHasToken(r.Header, "Connection", "upgrade") || key == "" {
+		return nil, fmt.Errorf("expected a websocket handshake")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("connection doesn't support websockets")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocketConn{conn: conn, rw: rw}, nil
+}
+
+func (ws *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := ws.rw.Write(payload); err != nil {
+		return err
+	}
+
+	return ws.rw.Flush()
+}
+
+//WriteText sends a text frame
+func (ws *websocketConn) WriteText(payload []byte) error {
+	return ws.writeFrame(websocketText, payload)
+}
+
+//readFrame reads the next client frame, client frames are always masked
+func (ws *websocketConn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.rw, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := header[0] & 0x0F
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > websocketMaxPayload {
+		return 0, nil, fmt.Errorf("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if header[1]&0x80 != 0 {
+		if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+/*
+Serve answers the client pings until the client closes the connection or the connection fails, the returned
+channel is closed then.
+*/
+func (ws *websocketConn) Serve() <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := ws.readFrame()
+			if err != nil {
+				return
+			}
+
+			switch opcode {
+			case websocketPing:
+				ws.writeFrame(websocketPong, payload)
+			case websocketClose:
+				ws.writeFrame(websocketClose, nil)
+				return
+			}
+		}
+	}()
+
+	return closed
+}
+
+//Close sends a close frame and closes the connection
+func (ws *websocketConn) Close() error {
+	ws.writeFrame(websocketClose, nil)
+	return ws.conn.Close()
+}
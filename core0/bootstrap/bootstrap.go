@@ -3,10 +3,12 @@ package bootstrap
 import (
 	"fmt"
 	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/process"
 	"github.com/g8os/core0/base/settings"
 	"github.com/g8os/core0/core0/network"
 	"github.com/op/go-logging"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -21,6 +23,9 @@ var (
 type Bootstrap struct {
 	i *settings.IncludedSettings
 	t settings.StartupTree
+
+	//serializes the service management commands
+	m sync.Mutex
 }
 
 func NewBootstrap() *Bootstrap {
@@ -46,15 +51,43 @@ func NewBootstrap() *Bootstrap {
 		t: t,
 	}
 
+	b.registerServiceCommands()
+	pm.RegisterBuiltIn(cmdReload, b.reload)
+
 	return b
 }
 
 //TODO: POC bootstrap. This will most probably get rewritten when the process is clearer
 
-func (b *Bootstrap) registerExtensions(extensions map[string]settings.Extension) {
+//registerExtensions registers the extensions commands, it returns the names of the registered extensions.
+func (b *Bootstrap) registerExtensions(extensions map[string]settings.Extension) []string {
+	var registered []string
 	for extKey, extCfg := range extensions {
-		pm.RegisterCmd(extKey, extCfg.Binary, extCfg.Cwd, extCfg.Args, extCfg.Env)
+		creds := process.Credentials{
+			User:        extCfg.User,
+			Group:       extCfg.Group,
+			Groups:      extCfg.Groups,
+			Umask:       extCfg.Umask,
+			Nice:        extCfg.Nice,
+			IONiceClass: extCfg.IONiceClass,
+			IONiceLevel: extCfg.IONiceLevel,
+			Rlimits:     extCfg.Rlimits,
+		}
+
+		if err := creds.Validate(); err != nil {
+			log.Errorf("Invalid credentials for extension '%s': %s", extKey, err)
+			continue
+		}
+
+		if extCfg.Daemon {
+			pm.RegisterDaemonCmd(extKey, extCfg.Binary, extCfg.Cwd, extCfg.Args, extCfg.Env, creds, extCfg.GetSocket(extKey))
+		} else {
+			pm.RegisterCmd(extKey, extCfg.Binary, extCfg.Cwd, extCfg.Args, extCfg.Env, creds)
+		}
+		registered = append(registered, extKey)
 	}
+
+	return registered
 }
 
 func (b *Bootstrap) startupServices(s, e settings.After) {
@@ -169,4 +202,6 @@ func (b *Bootstrap) Bootstrap() {
 
 	//start up all boot services ([boot, end] slice)
 	b.startupServices(settings.AfterBoot, settings.ToTheEnd)
+
+	go b.watchReload()
 }
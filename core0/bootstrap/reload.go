@@ -0,0 +1,195 @@
+package bootstrap
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"syscall"
+)
+
+const (
+	cmdReload = "core.reload"
+)
+
+//ReloadResult lists the changes applied by a configuration reload
+type ReloadResult struct {
+	RegisteredExtensions   []string `json:"registered_extensions"`
+	UnregisteredExtensions []string `json:"unregistered_extensions"`
+	StartedServices        []string `json:"started_services"`
+	StoppedServices        []string `json:"stopped_services"`
+	//RestartRequired lists the changed settings that are only applied on restart (ex: sink.main, logging.console)
+	RestartRequired []string `json:"restart_required"`
+}
+
+//extensions merges the extensions of the main config file and the include directory
+func extensions(included *settings.IncludedSettings) map[string]settings.Extension {
+	all := make(map[string]settings.Extension)
+	for key, ext := range settings.Current().Extension {
+		all[key] = ext
+	}
+
+	for key, ext := range included.Extension {
+		all[key] = ext
+	}
+
+	return all
+}
+
+func services(tree settings.StartupTree) map[string]settings.Startup {
+	all := make(map[string]settings.Startup)
+	for _, s := range tree.Services() {
+		all[s.Key()] = s
+	}
+
+	return all
+}
+
+/*
+Reload loads the configuration again and applies the differences. Extensions that were added or changed are
+registered and removed ones are unregistered. Services that were removed or changed are stopped (with their
+dependents), then new services and the stopped services that are still defined are started. Services whose
+definition didn't change are left alone. Reload returns once the started services are running, or the context is done.
+*/
+func (b *Bootstrap) Reload(ctx context.Context) (*ReloadResult, error) {
+	result, up, err := b.apply()
+	if err != nil {
+		return nil, err
+	}
+
+	//services are waited for without the lock, so service commands are not held back by a slow service
+	if err := up.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//apply applies the reloaded configuration, the services are started but not waited for
+func (b *Bootstrap) apply() (*ReloadResult, *startup, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	oldExtensions := extensions(b.i)
+	oldServices := services(b.t)
+
+	restart, err := settings.ReloadSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, section := range restart {
+		log.Warningf("Settings of '%s' changed, they will only apply after a restart", section)
+	}
+
+	included, errors := settings.Current().GetIncludedSettings()
+	for _, err := range errors {
+		log.Errorf("%s", err)
+	}
+
+	tree, errors := included.GetStartupTree()
+	for _, err := range errors {
+		log.Errorf("%s", err)
+	}
+
+	result := &ReloadResult{
+		RegisteredExtensions:   make([]string, 0),
+		UnregisteredExtensions: make([]string, 0),
+		StartedServices:        make([]string, 0),
+		StoppedServices:        make([]string, 0),
+		RestartRequired:        restart,
+	}
+
+	if result.RestartRequired == nil {
+		result.RestartRequired = make([]string, 0)
+	}
+
+	newExtensions := extensions(included)
+	for key := range oldExtensions {
+		if _, ok := newExtensions[key]; !ok {
+			log.Infof("Unregistering extension '%s'", key)
+			pm.UnregisterCmd(key)
+			result.UnregisteredExtensions = append(result.UnregisteredExtensions, key)
+		}
+	}
+
+	changed := make(map[string]settings.Extension)
+	for key, ext := range newExtensions {
+		if old, ok := oldExtensions[key]; !ok || !reflect.DeepEqual(old, ext) {
+			changed[key] = ext
+		}
+	}
+
+	result.RegisteredExtensions = append(result.RegisteredExtensions, b.registerExtensions(changed)...)
+
+	//services are stopped using the old tree, so dependents are found according to the old definitions
+	newServices := services(tree)
+	for key, old := range oldServices {
+		if s, ok := newServices[key]; ok && reflect.DeepEqual(old, s) {
+			continue
+		}
+
+		stopped, err := b.stop(key)
+		if err != nil {
+			log.Errorf("Failed to stop service '%s': %s", key, err)
+		}
+
+		result.StoppedServices = append(result.StoppedServices, stopped...)
+	}
+
+	b.i, b.t = included, tree
+
+	var start []string
+	for key := range newServices {
+		if _, ok := oldServices[key]; !ok {
+			start = append(start, key)
+		}
+	}
+
+	for _, key := range result.StoppedServices {
+		if _, ok := newServices[key]; ok {
+			start = append(start, key)
+		}
+	}
+
+	up, err := b.start(start...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result.StartedServices = append(result.StartedServices, up.Names()...)
+	for _, list := range [][]string{
+		result.RegisteredExtensions,
+		result.UnregisteredExtensions,
+		result.StartedServices,
+		result.StoppedServices,
+	} {
+		sort.Strings(list)
+	}
+
+	return result, up, nil
+}
+
+func (b *Bootstrap) reload(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	return b.Reload(ctx)
+}
+
+//watchReload reloads the configuration on SIGHUP
+func (b *Bootstrap) watchReload() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		log.Infof("Reloading configuration")
+		result, err := b.Reload(context.Background())
+		if err != nil {
+			log.Errorf("Failed to reload configuration: %s", err)
+			continue
+		}
+
+		log.Infof("Configuration reloaded: %+v", *result)
+	}
+}
@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadChangedService(t *testing.T) {
+	b, dir := testBootstrap(t, testService)
+	defer os.RemoveAll(dir)
+	defer stopAll()
+
+	up, err := b.start("svc")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Nil(t, up.Wait(context.Background()))
+
+	for i := 0; i < 5; i++ {
+		before, _ := pm.GetManager().Runner("svc")
+
+		//a changed definition stops the service and starts it again right away
+		changed := strings.Replace(testService, "sleep 100", fmt.Sprintf("sleep %d", 101+i), 1)
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, "conf", "svc.toml"), []byte(changed), 0644))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, err := b.Reload(ctx)
+		cancel()
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		assert.Equal(t, []string{"svc"}, result.StoppedServices)
+		assert.Equal(t, []string{"svc"}, result.StartedServices)
+
+		after, ok := pm.GetManager().Runner("svc")
+		if assert.True(t, ok, "service is not running after reload %d", i) {
+			assert.True(t, before != after, "service was not restarted")
+		}
+	}
+}
+
+func TestReloadChangedDaemon(t *testing.T) {
+	b, dir := testBootstrap(t, "")
+	defer os.RemoveAll(dir)
+	defer stopAll()
+
+	for i := 0; i < 5; i++ {
+		before, _ := pm.GetManager().Runner(pm.DaemonJobID("ext"))
+
+		//the daemon is killed and started again with the same job id
+		ext := fmt.Sprintf(`
+[extension.ext]
+binary = "sh"
+args = ["-c", "exec sleep %d"]
+daemon = true
+socket = "%s"
+`, 100+i, path.Join(dir, "ext.sock"))
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, "conf", "svc.toml"), []byte(ext), 0644))
+
+		result, err := b.Reload(context.Background())
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []string{"ext"}, result.RegisteredExtensions)
+
+		after, ok := pm.GetManager().Runner(pm.DaemonJobID("ext"))
+		if assert.True(t, ok, "daemon is not running after reload %d", i) {
+			assert.True(t, before != after, "daemon was not restarted")
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cmdServiceList    = "service.list"
+	cmdServiceStatus  = "service.status"
+	cmdServiceStart   = "service.start"
+	cmdServiceStop    = "service.stop"
+	cmdServiceRestart = "service.restart"
+
+	//ServiceStartTimeout max time to wait for started services to be running
+	ServiceStartTimeout = 2 * time.Minute
+
+	ServiceRunning = "running"
+	ServiceWaiting = "waiting"
+	ServiceStopped = "stopped"
+)
+
+//ServiceStatus is the runtime state of a startup service
+type ServiceStatus struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	After   []string `json:"after"`
+	//State running, waiting (for a restart) or stopped
+	State string `json:"state"`
+	//Uptime in seconds of the running process
+	Uptime int64 `json:"uptime"`
+	//CrashLoop is set if the service is restarted over and over with no stable run
+	CrashLoop bool `json:"crash_loop"`
+}
+
+type serviceStatuses []ServiceStatus
+
+func (s serviceStatuses) Len() int           { return len(s) }
+func (s serviceStatuses) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s serviceStatuses) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+//stopOrder sorts services by descending number of dependencies, a service always has more dependencies than any of
+//its own dependencies so the dependents come first.
+type stopOrder struct {
+	services []settings.Startup
+	depth    map[string]int
+}
+
+func (s *stopOrder) Len() int      { return len(s.services) }
+func (s *stopOrder) Swap(i, j int) { s.services[i], s.services[j] = s.services[j], s.services[i] }
+func (s *stopOrder) Less(i, j int) bool {
+	return s.depth[s.services[i].Key()] > s.depth[s.services[j].Key()]
+}
+
+type serviceData struct {
+	Name string `json:"name"`
+}
+
+func (b *Bootstrap) registerServiceCommands() {
+	pm.RegisterBuiltIn(cmdServiceList, b.serviceList)
+	pm.RegisterBuiltIn(cmdServiceStatus, b.serviceStatus)
+	pm.RegisterBuiltIn(cmdServiceStart, b.serviceStart)
+	pm.RegisterBuiltIn(cmdServiceStop, b.serviceStop)
+	pm.RegisterBuiltIn(cmdServiceRestart, b.serviceRestart)
+}
+
+//service gets the startup service with the given name, only services that are part of the startup tree are returned.
+func (b *Bootstrap) service(name string) (settings.Startup, error) {
+	for _, s := range b.t.Services() {
+		if s.Key() == name {
+			return s, nil
+		}
+	}
+
+	return settings.Startup{}, fmt.Errorf("unknown service '%s'", name)
+}
+
+func (b *Bootstrap) status(s settings.Startup) ServiceStatus {
+	status := ServiceStatus{
+		Name:    s.Key(),
+		Command: s.Name,
+		After:   s.After,
+		State:   ServiceStopped,
+	}
+
+	if status.After == nil {
+		status.After = []string{}
+	}
+
+	runner, ok := pm.GetManager().Runner(s.Key())
+	if !ok {
+		return status
+	}
+
+	status.State = ServiceWaiting
+	status.CrashLoop = runner.CrashLoop()
+	if started := runner.StartTime(); !started.IsZero() {
+		status.State = ServiceRunning
+		status.Uptime = int64(time.Since(started) / time.Second)
+	}
+
+	return status
+}
+
+func (b *Bootstrap) running(name string) bool {
+	_, ok := pm.GetManager().Runner(name)
+	return ok
+}
+
+//startup tracks the services started by start until they are running
+type startup struct {
+	slice settings.StartupSlice
+	state pm.StateMachine
+}
+
+//Names gets the names of the started services
+func (s *startup) Names() []string {
+	names := make([]string, 0, len(s.slice))
+	for _, service := range s.slice {
+		names = append(names, service.Key())
+	}
+
+	return names
+}
+
+/*
+Wait waits for the started services to be running. An error is returned if one of them failed to start, or if they
+are not all running once the context is done or after ServiceStartTimeout. It must not be called with the bootstrap
+lock held, services can take long to come up.
+*/
+func (s *startup) Wait(ctx context.Context) error {
+	if len(s.slice) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ServiceStartTimeout)
+	defer cancel()
+
+	var failed, pending []string
+	for _, service := range s.slice {
+		running, err := s.state.WaitContext(ctx, service.Key())
+		if err != nil {
+			pending = append(pending, service.Key())
+		} else if !running {
+			failed = append(failed, service.Key())
+		}
+	}
+
+	var errors []string
+	if len(failed) > 0 {
+		errors = append(errors, fmt.Sprintf("failed to start: %s", strings.Join(failed, ", ")))
+	}
+
+	if len(pending) > 0 {
+		errors = append(errors, fmt.Sprintf("not running yet: %s", strings.Join(pending, ", ")))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("services %s", strings.Join(errors, ", "))
+	}
+
+	return nil
+}
+
+/*
+start starts the given services and all their dependencies that are not running yet, dependencies are started first.
+The returned startup can be waited for the services to be running.
+*/
+func (b *Bootstrap) start(names ...string) (*startup, error) {
+	var slice settings.StartupSlice
+	added := make(map[string]bool)
+
+	for _, name := range names {
+		for _, key := range append(b.i.Dependencies(name), name) {
+			if added[key] || b.running(key) {
+				continue
+			}
+
+			s, err := b.service(key)
+			if err != nil {
+				return nil, err
+			}
+
+			added[key] = true
+			slice = append(slice, s)
+		}
+	}
+
+	up := &startup{slice: slice}
+	if len(slice) > 0 {
+		//the slice makes sure a service only starts once its dependencies in the slice are running
+		up.state = pm.GetManager().StartSlice(slice)
+	}
+
+	return up, nil
+}
+
+/*
+stop stops the service and all the running services that depend on it, dependents are stopped first. It returns the
+names of the stopped services.
+*/
+func (b *Bootstrap) stop(name string) ([]string, error) {
+	s, err := b.service(name)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &stopOrder{
+		services: []settings.Startup{s},
+		depth:    make(map[string]int),
+	}
+
+	for _, key := range b.i.Dependents(name) {
+		if d, err := b.service(key); err == nil {
+			order.services = append(order.services, d)
+		}
+	}
+
+	for _, s := range order.services {
+		order.depth[s.Key()] = len(b.i.Dependencies(s.Key()))
+	}
+
+	sort.Sort(order)
+
+	var stopped []string
+	for _, s := range order.services {
+		runner, ok := pm.GetManager().Runner(s.Key())
+		if !ok {
+			continue
+		}
+
+		log.Infof("Stopping service '%s'", s.Key())
+		runner.Kill()
+		runner.Wait()
+		stopped = append(stopped, s.Key())
+	}
+
+	return stopped, nil
+}
+
+func getServiceName(cmd *core.Command) (string, error) {
+	var data serviceData
+	if err := json.Unmarshal(*cmd.Arguments, &data); err != nil {
+		return "", err
+	}
+
+	if data.Name == "" {
+		return "", fmt.Errorf("service name is required")
+	}
+
+	return data.Name, nil
+}
+
+func (b *Bootstrap) serviceList(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	services := make(serviceStatuses, 0)
+	for _, s := range b.t.Services() {
+		services = append(services, b.status(s))
+	}
+
+	sort.Sort(services)
+
+	return services, nil
+}
+
+func (b *Bootstrap) serviceStatus(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getServiceName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	s, err := b.service(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.status(s), nil
+}
+
+func (b *Bootstrap) serviceStart(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getServiceName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	s, err := b.service(name)
+	if err != nil {
+		b.m.Unlock()
+		return nil, err
+	}
+
+	if b.running(name) {
+		b.m.Unlock()
+		return nil, fmt.Errorf("service '%s' is already running", name)
+	}
+
+	up, err := b.start(name)
+	b.m.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := up.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return b.status(s), nil
+}
+
+func (b *Bootstrap) serviceStop(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getServiceName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if !b.running(name) {
+		return nil, fmt.Errorf("service '%s' is not running", name)
+	}
+
+	return b.stop(name)
+}
+
+func (b *Bootstrap) serviceRestart(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	name, err := getServiceName(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	s, err := b.service(name)
+	if err != nil {
+		b.m.Unlock()
+		return nil, err
+	}
+
+	stopped, err := b.stop(name)
+	if err != nil {
+		b.m.Unlock()
+		return nil, err
+	}
+
+	//the service is started even if it wasn't running, and the dependents that were stopped are brought back.
+	up, err := b.start(append(stopped, name)...)
+	b.m.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := up.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return b.status(s), nil
+}
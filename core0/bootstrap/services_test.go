@@ -0,0 +1,95 @@
+package bootstrap
+
+import (
+	"context"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+	"github.com/g8os/core0/base/settings"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testService = `
+[startup.svc]
+name = "core.system"
+running_match = "ready"
+
+[startup.svc.args]
+name = "sh"
+args = ["-c", "echo ready; exec sleep 100"]
+`
+
+//pmOnce runs a single process manager for all the tests, since managers don't share the exit status of the children
+var pmOnce sync.Once
+
+//testBootstrap loads a config that includes the given service definitions, and runs a process manager
+func testBootstrap(t *testing.T, services string) (*Bootstrap, string) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	include := path.Join(dir, "conf")
+	main := path.Join(dir, "g8os.toml")
+	assert.Nil(t, os.Mkdir(include, 0755))
+	assert.Nil(t, ioutil.WriteFile(main, []byte("[main]\ninclude = \""+include+"\"\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(path.Join(include, "svc.toml"), []byte(services), 0644))
+
+	if !assert.Nil(t, settings.LoadSettings(main)) {
+		t.FailNow()
+	}
+
+	pmOnce.Do(func() {
+		pm.InitProcessManager(10).Run()
+	})
+
+	return NewBootstrap(), dir
+}
+
+//stopAll kills the jobs left by the test
+func stopAll() {
+	for _, runner := range pm.GetManager().RunnerList() {
+		runner.Kill()
+		runner.Wait()
+	}
+}
+
+func TestServiceRestart(t *testing.T) {
+	b, dir := testBootstrap(t, testService)
+	defer os.RemoveAll(dir)
+	defer stopAll()
+
+	up, err := b.start("svc")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Nil(t, up.Wait(context.Background()))
+
+	cmd := &core.Command{Arguments: core.MustArguments(serviceData{Name: "svc"})}
+	for i := 0; i < 10; i++ {
+		before, _ := pm.GetManager().Runner("svc")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		status, err := b.serviceRestart(ctx, cmd)
+		cancel()
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, ServiceRunning, status.(ServiceStatus).State)
+
+		after, ok := pm.GetManager().Runner("svc")
+		if assert.True(t, ok, "service is not running after restart %d", i) {
+			assert.True(t, before != after, "service was not restarted")
+		}
+	}
+
+	//make sure the service is not removed later by a late clean up
+	time.Sleep(100 * time.Millisecond)
+	_, ok := pm.GetManager().Runner("svc")
+	assert.True(t, ok)
+}
@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/g8os/core0/base/pm"
@@ -13,9 +14,9 @@ import (
 )
 
 func init() {
-	pm.CmdMap["bridge.create"] = process.NewInternalProcessFactory(bridgeCreate)
-	pm.CmdMap["bridge.list"] = process.NewInternalProcessFactory(bridgeList)
-	pm.CmdMap["bridge.delete"] = process.NewInternalProcessFactory(bridgeDelete)
+	pm.RegisterBuiltIn("bridge.create", bridgeCreate)
+	pm.RegisterBuiltIn("bridge.list", bridgeList)
+	pm.RegisterBuiltIn("bridge.delete", bridgeDelete)
 }
 
 const (
@@ -179,7 +180,7 @@ func bridgeDnsMasqNetworking(bridge *netlink.Bridge, network *BridgeNetwork) (*n
 	return addr, nil
 }
 
-func bridgeNetworking(bridge *netlink.Bridge, network *BridgeNetwork) error {
+func bridgeNetworking(ctx context.Context, bridge *netlink.Bridge, network *BridgeNetwork) error {
 	var addr *netlink.Addr
 	var err error
 	switch network.Mode {
@@ -209,16 +210,20 @@ func bridgeNetworking(bridge *netlink.Bridge, network *BridgeNetwork) error {
 			),
 		}
 
-		_, err := pm.GetManager().RunCmd(nat)
+		result, err := pm.GetManager().RunCmdWait(ctx, nat)
 		if err != nil {
 			return err
 		}
+
+		if result.State != core.StateSuccess {
+			return fmt.Errorf("failed to setup nat: %v", result.Streams)
+		}
 	}
 
 	return nil
 }
 
-func bridgeCreate(cmd *core.Command) (interface{}, error) {
+func bridgeCreate(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args BridgeCreateArguments
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
 		return nil, err
@@ -249,8 +254,9 @@ func bridgeCreate(cmd *core.Command) (interface{}, error) {
 		return nil, err
 	}
 
-	if err := bridgeNetworking(bridge, &args.Network); err != nil {
+	if err := bridgeNetworking(ctx, bridge, &args.Network); err != nil {
 		//delete bridge?
+		pm.GetManager().Kill(fmt.Sprintf("dnsmasq-%s", bridge.Name))
 		netlink.LinkDel(bridge)
 		return nil, err
 	}
@@ -258,7 +264,7 @@ func bridgeCreate(cmd *core.Command) (interface{}, error) {
 	return nil, nil
 }
 
-func bridgeList(cmd *core.Command) (interface{}, error) {
+func bridgeList(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	links, err := netlink.LinkList()
 	if err != nil {
 		return nil, err
@@ -274,7 +280,7 @@ func bridgeList(cmd *core.Command) (interface{}, error) {
 	return bridges, nil
 }
 
-func bridgeDelete(cmd *core.Command) (interface{}, error) {
+func bridgeDelete(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args BridgeDeleteArguments
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,11 +19,11 @@ var (
 )
 
 func init() {
-	pm.CmdMap["btrfs.list"] = process.NewInternalProcessFactory(btrfsList)
-	pm.CmdMap["btrfs.create"] = process.NewInternalProcessFactory(btrfsCreate)
-	pm.CmdMap["btrfs.subvol_create"] = process.NewInternalProcessFactory(btrfsSubvolCreate)
-	pm.CmdMap["btrfs.subvol_delete"] = process.NewInternalProcessFactory(btrfsSubvolDelete)
-	pm.CmdMap["btrfs.subvol_list"] = process.NewInternalProcessFactory(btrfsSubvolList)
+	pm.RegisterBuiltIn("btrfs.list", btrfsList)
+	pm.RegisterBuiltIn("btrfs.create", btrfsCreate)
+	pm.RegisterBuiltIn("btrfs.subvol_create", btrfsSubvolCreate)
+	pm.RegisterBuiltIn("btrfs.subvol_delete", btrfsSubvolDelete)
+	pm.RegisterBuiltIn("btrfs.subvol_list", btrfsSubvolList)
 }
 
 type btrfsFS struct {
@@ -82,7 +83,7 @@ func (arg btrfsCreateArgument) Validate() error {
 	return nil
 }
 
-func btrfsCreate(cmd *core.Command) (interface{}, error) {
+func btrfsCreate(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args btrfsCreateArgument
 	var opts []string
 
@@ -104,7 +105,7 @@ func btrfsCreate(cmd *core.Command) (interface{}, error) {
 	}
 	opts = append(opts, strings.Join(args.Devices, " "))
 
-	result, err := runBtrfsCmd("mkfs.btrfs", opts)
+	result, err := runBtrfsCmd(ctx, "mkfs.btrfs", opts)
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +118,8 @@ func btrfsCreate(cmd *core.Command) (interface{}, error) {
 }
 
 // list btrfs FSs
-func btrfsList(cmd *core.Command) (interface{}, error) {
-	result, err := runBtrfsCmd("btrfs", []string{"filesystem", "show", "--raw"})
+func btrfsList(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	result, err := runBtrfsCmd(ctx, "btrfs", []string{"filesystem", "show", "--raw"})
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +140,7 @@ type btrfsSubvolArgument struct {
 }
 
 // create subvolume under a mount point
-func btrfsSubvolCreate(cmd *core.Command) (interface{}, error) {
+func btrfsSubvolCreate(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args btrfsSubvolArgument
 
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
@@ -149,7 +150,7 @@ func btrfsSubvolCreate(cmd *core.Command) (interface{}, error) {
 		return nil, fmt.Errorf("invalid path=%v", args.Path)
 	}
 
-	result, err := runBtrfsCmd("btrfs", []string{"subvolume", "create", args.Path})
+	result, err := runBtrfsCmd(ctx, "btrfs", []string{"subvolume", "create", args.Path})
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +162,7 @@ func btrfsSubvolCreate(cmd *core.Command) (interface{}, error) {
 }
 
 // delete subvolume under a mount point
-func btrfsSubvolDelete(cmd *core.Command) (interface{}, error) {
+func btrfsSubvolDelete(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args btrfsSubvolArgument
 
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
@@ -171,7 +172,7 @@ func btrfsSubvolDelete(cmd *core.Command) (interface{}, error) {
 		return nil, fmt.Errorf("invalid path=%v", args.Path)
 	}
 
-	result, err := runBtrfsCmd("btrfs", []string{"subvolume", "delete", args.Path})
+	result, err := runBtrfsCmd(ctx, "btrfs", []string{"subvolume", "delete", args.Path})
 	if err != nil {
 		return nil, err
 	}
@@ -184,7 +185,7 @@ func btrfsSubvolDelete(cmd *core.Command) (interface{}, error) {
 }
 
 // list subvolume under a mount point
-func btrfsSubvolList(cmd *core.Command) (interface{}, error) {
+func btrfsSubvolList(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args btrfsSubvolArgument
 
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
@@ -194,7 +195,7 @@ func btrfsSubvolList(cmd *core.Command) (interface{}, error) {
 		return nil, fmt.Errorf("invalid path=%v", args.Path)
 	}
 
-	result, err := runBtrfsCmd("btrfs", []string{"subvolume", "list", args.Path})
+	result, err := runBtrfsCmd(ctx, "btrfs", []string{"subvolume", "list", args.Path})
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +207,7 @@ func btrfsSubvolList(cmd *core.Command) (interface{}, error) {
 	return btrfsParseSubvolList(result.Streams[0])
 }
 
-func runBtrfsCmd(cmd string, args []string) (*core.JobResult, error) {
+func runBtrfsCmd(ctx context.Context, cmd string, args []string) (*core.JobResult, error) {
 	shellCmd := &core.Command{
 		ID:      uuid.New(),
 		Command: process.CommandSystem,
@@ -218,12 +219,7 @@ func runBtrfsCmd(cmd string, args []string) (*core.JobResult, error) {
 		),
 	}
 
-	runner, err := pm.GetManager().RunCmd(shellCmd)
-	if err != nil {
-		return nil, err
-	}
-
-	return runner.Wait(), nil
+	return pm.GetManager().RunCmdWait(ctx, shellCmd)
 }
 func btrfsParseSubvolList(out string) ([]btrfsSubvol, error) {
 	var svs []btrfsSubvol
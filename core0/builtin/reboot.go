@@ -1,9 +1,9 @@
 package builtin
 
 import (
+	"context"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"syscall"
 )
 
@@ -12,10 +12,10 @@ const (
 )
 
 func init() {
-	pm.CmdMap[cmdReboot] = process.NewInternalProcessFactory(restart)
+	pm.RegisterBuiltIn(cmdReboot, restart)
 }
 
-func restart(cmd *core.Command) (interface{}, error) {
+func restart(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	pm.GetManager().Killall()
 	syscall.Sync()
 	syscall.Reboot(syscall.LINUX_REBOOT_CMD_RESTART)
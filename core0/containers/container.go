@@ -1,17 +1,21 @@
 package containers
 
 import (
+	"context"
 	"fmt"
 	"github.com/g8os/core0/base/logger"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/pm/process"
+	"github.com/g8os/core0/base/settings"
 	"github.com/pborman/uuid"
 	"github.com/vishvananda/netlink"
 	"net"
 	"os"
 	"os/exec"
 	"path"
+	"sort"
+	"strings"
 	"syscall"
 )
 
@@ -35,10 +39,11 @@ func newContainer(id uint16, route core.Route, args *ContainerCreateArguments) *
 	}
 }
 
-func (c *container) Start() error {
+//Start prepares the container root and starts coreX inside it, the preparation is aborted if ctx is cancelled.
+func (c *container) Start(ctx context.Context) error {
 	coreID := fmt.Sprintf("core-%d", c.id)
 
-	if err := c.mount(); err != nil {
+	if err := c.mount(ctx); err != nil {
 		c.cleanup()
 		return err
 	}
@@ -47,6 +52,11 @@ func (c *container) Start() error {
 		c.cleanup()
 		return err
 	}
+
+	if err := ctx.Err(); err != nil {
+		c.cleanup()
+		return err
+	}
 	//
 	mgr := pm.GetManager()
 	extCmd := &core.Command{
@@ -58,12 +68,12 @@ func (c *container) Start() error {
 				Name:   "/coreX",
 				Chroot: c.root(),
 				Dir:    "/",
-				Args: []string{
+				Args: append([]string{
 					"-core-id", fmt.Sprintf("%d", c.id),
 					"-redis-socket", "/redis.socket",
 					"-reply-to", coreXResponseQueue,
                     "-hostname", c.args.Hostname,
-				},
+				}, signatureArgs()...),
 				Env: map[string]string{
 					"PATH": "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
 				},
@@ -87,6 +97,35 @@ func (c *container) Start() error {
 	return nil
 }
 
+/*
+signatureArgs gets the coreX options to verify the commands like core0 does, the redis socket is shared by all the
+containers so coreX can't trust the commands it receives.
+*/
+func signatureArgs() []string {
+	cfg := settings.Settings.Signature
+
+	var args []string
+	if cfg.Required {
+		args = append(args, "-signature-required")
+	}
+
+	if cfg.Window > 0 {
+		args = append(args, "-signature-window", fmt.Sprintf("%d", cfg.Window))
+	}
+
+	keys := make([]string, 0, len(cfg.Keys))
+	for id, key := range cfg.Keys {
+		keys = append(keys, fmt.Sprintf("%s=%s", id, key))
+	}
+
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		args = append(args, "-trusted-keys", strings.Join(keys, ","))
+	}
+
+	return args
+}
+
 func (c *container) preStart() error {
 	//mount up redis socket, coreX binary, etc...
 	root := c.root()
@@ -1,6 +1,7 @@
 package containers
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"github.com/g8os/core0/base/settings"
@@ -24,7 +25,7 @@ func (c *container) name() string {
 	return fmt.Sprintf("container-%d", c.id)
 }
 
-func (c *container) getPlist(src string) (string, error) {
+func (c *container) getPlist(ctx context.Context, src string) (string, error) {
 	u, err := url.Parse(src)
 	if err != nil {
 		return "", err
@@ -38,7 +39,12 @@ func (c *container) getPlist(src string) (string, error) {
 		}
 		return u.Path, nil
 	} else if u.Scheme == "http" || u.Scheme == "https" {
-		response, err := http.Get(src)
+		request, err := http.NewRequest("GET", src, nil)
+		if err != nil {
+			return "", err
+		}
+
+		response, err := http.DefaultClient.Do(request.WithContext(ctx))
 		if err != nil {
 			return "", err
 		}
@@ -63,7 +69,7 @@ func (c *container) getPlist(src string) (string, error) {
 	return "", fmt.Errorf("invalid plist url %s", src)
 }
 
-func (c *container) mountPList(src string, target string) error {
+func (c *container) mountPList(ctx context.Context, src string, target string) error {
 	//check
 	hash := c.hash(src)
 	backend := path.Join(BackendBaseDir, c.name(), hash)
@@ -78,7 +84,7 @@ func (c *container) mountPList(src string, target string) error {
 		}
 	}
 
-	plist, err := c.getPlist(src)
+	plist, err := c.getPlist(ctx, src)
 	if err != nil {
 		return err
 	}
@@ -93,7 +99,7 @@ func (c *container) mountPList(src string, target string) error {
 	}
 
 	storageConfig := &config.StorConfig{
-		URL: settings.Settings.Globals.Get("fuse_storage", "https://stor.jumpscale.org/stor2"),
+		URL: settings.Current().Globals.Get("fuse_storage", "https://stor.jumpscale.org/stor2"),
 	}
 
 	storage, err := storageConfig.GetStorClient()
@@ -123,18 +129,22 @@ func (c *container) root() string {
 	return path.Join(ContainerBaseRootDir, c.name())
 }
 
-func (c *container) mount() error {
+func (c *container) mount(ctx context.Context) error {
 	//mount root plist.
 	//prepare root folder.
 	root := c.root()
 	log.Debugf("Container root: %s", root)
 	os.RemoveAll(root)
 
-	if err := c.mountPList(c.args.Root, root); err != nil {
+	if err := c.mountPList(ctx, c.args.Root, root); err != nil {
 		return err
 	}
 
 	for src, dst := range c.args.Mount {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		target := path.Join(root, dst)
 		if err := os.MkdirAll(target, 0755); err != nil {
 			return err
@@ -151,7 +161,7 @@ func (c *container) mount() error {
 			}
 		} else {
 			//assume a plist
-			if err := c.mountPList(src, target); err != nil {
+			if err := c.mountPList(ctx, src, target); err != nil {
 				return err
 			}
 		}
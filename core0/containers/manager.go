@@ -1,6 +1,7 @@
 package containers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -137,6 +138,17 @@ type containerManager struct {
 	ensure sync.Once
 
 	sinks map[string]base.SinkClient
+
+	//routes of the signed commands dispatched to the containers by job id, their tags can't be set to the route
+	routes   map[string]dispatched
+	routesMx sync.Mutex
+}
+
+//dispatched is a signed command that was dispatched to a container
+type dispatched struct {
+	route core.Route
+	//recurring commands have many results, so their route is kept
+	recurring bool
 }
 
 /*
@@ -150,8 +162,9 @@ TODO:
 
 func ContainerSubsystem(sinks map[string]base.SinkClient) error {
 	containerMgr := &containerManager{
-		pool:  utils.NewRedisPool("unix", redisSocketSrc, ""),
-		sinks: sinks,
+		pool:   utils.NewRedisPool("unix", redisSocketSrc, ""),
+		sinks:  sinks,
+		routes: make(map[string]dispatched),
 	}
 
 	script, err := assets.Asset("scripts/network.sh")
@@ -167,12 +180,12 @@ func ContainerSubsystem(sinks map[string]base.SinkClient) error {
 		return err
 	}
 
-	pm.RegisterCmd(zeroTierCommand, "sh", "/", []string{zeroTierScriptPath, "{netns}", "{zerotier}"}, nil)
+	pm.RegisterCmd(zeroTierCommand, "sh", "/", []string{zeroTierScriptPath, "{netns}", "{zerotier}"}, nil, process.Credentials{})
 
-	pm.CmdMap[cmdContainerCreate] = process.NewInternalProcessFactory(containerMgr.create)
-	pm.CmdMap[cmdContainerList] = process.NewInternalProcessFactory(containerMgr.list)
-	pm.CmdMap[cmdContainerDispatch] = process.NewInternalProcessFactory(containerMgr.dispatch)
-	pm.CmdMap[cmdContainerTerminate] = process.NewInternalProcessFactory(containerMgr.terminate)
+	pm.RegisterBuiltIn(cmdContainerCreate, containerMgr.create)
+	pm.RegisterBuiltIn(cmdContainerList, containerMgr.list)
+	pm.RegisterBuiltIn(cmdContainerDispatch, containerMgr.dispatch)
+	pm.RegisterBuiltIn(cmdContainerTerminate, containerMgr.terminate)
 
 	if err := containerMgr.setUpDefaultBridge(); err != nil {
 		return err
@@ -228,17 +241,35 @@ func (m *containerManager) forwardNext() error {
 		return nil //no wait.
 	}
 
-	//use command tags for routing.
-	if sink, ok := m.sinks[result.Tags]; ok {
-		log.Debugf("Forwarding job result to %s", result.Tags)
+	//use command tags for routing, unless the command was signed.
+	route := result.Tags
+	if r, ok := m.route(result.ID); ok {
+		route = string(r)
+	}
+
+	if sink, ok := m.sinks[route]; ok {
+		log.Debugf("Forwarding job result to %s", route)
 		return sink.Respond(&result)
 	} else {
-		log.Warningf("Received a corex result for an unknown sink: %s", result.Tags)
+		log.Warningf("Received a corex result for an unknown sink: %s", route)
 	}
 
 	return nil
 }
 
+//route gets the route of a signed dispatched command, it's forgotten after its result unless the command is recurring
+func (m *containerManager) route(id string) (core.Route, bool) {
+	m.routesMx.Lock()
+	defer m.routesMx.Unlock()
+
+	d, ok := m.routes[id]
+	if ok && !d.recurring {
+		delete(m.routes, id)
+	}
+
+	return d.route, ok
+}
+
 func (m *containerManager) startForwarder() {
 	log.Debugf("Start container results forwarder")
 	for {
@@ -256,7 +287,7 @@ func (m *containerManager) getNextSequence() uint16 {
 	return m.sequence
 }
 
-func (m *containerManager) create(cmd *core.Command) (interface{}, error) {
+func (m *containerManager) create(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args ContainerCreateArguments
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
 		return nil, err
@@ -269,14 +300,14 @@ func (m *containerManager) create(cmd *core.Command) (interface{}, error) {
 	id := m.getNextSequence()
 	c := newContainer(id, cmd.Route, &args)
 
-	if err := c.Start(); err != nil {
+	if err := c.Start(ctx); err != nil {
 		return nil, err
 	}
 
 	return id, nil
 }
 
-func (m *containerManager) list(cmd *core.Command) (interface{}, error) {
+func (m *containerManager) list(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	containers := make(map[uint64]*process.ProcessStats)
 
 	for name, runner := range pm.GetManager().Runners() {
@@ -301,7 +332,7 @@ func (m *containerManager) getCoreXQueue(id uint16) string {
 	return fmt.Sprintf("core:%v", id)
 }
 
-func (m *containerManager) dispatch(cmd *core.Command) (interface{}, error) {
+func (m *containerManager) dispatch(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args ContainerDispatchArguments
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
 		return nil, err
@@ -311,32 +342,48 @@ func (m *containerManager) dispatch(cmd *core.Command) (interface{}, error) {
 		return nil, fmt.Errorf("invalid container id")
 	}
 
-	if _, ok := pm.GetManager().Runners()[fmt.Sprintf("core-%d", args.Container)]; !ok {
+	if _, ok := pm.GetManager().Runner(fmt.Sprintf("core-%d", args.Container)); !ok {
 		return nil, fmt.Errorf("container does not exist")
 	}
 
-	id := uuid.New()
-	args.Command.ID = id
-	args.Command.Tags = string(cmd.Route)
+	var data []byte
+	if args.Command.Signature != nil {
+		//signed commands are forwarded as they were signed so coreX can verify them, their results are routed by id
+		if args.Command.ID == "" {
+			return nil, fmt.Errorf("signed commands must have an id")
+		}
+
+		data = args.Command.Payload()
 
-	db := m.pool.Get()
-	defer db.Close()
+		m.routesMx.Lock()
+		m.routes[args.Command.ID] = dispatched{
+			route:     cmd.Route,
+			recurring: args.Command.RecurringPeriod > 0 || args.Command.Schedule != "",
+		}
+		m.routesMx.Unlock()
+	} else {
+		args.Command.ID = uuid.New()
+		args.Command.Tags = string(cmd.Route)
 
-	data, err := json.Marshal(args.Command)
-	if err != nil {
-		return nil, err
+		var err error
+		if data, err = json.Marshal(args.Command); err != nil {
+			return nil, err
+		}
 	}
 
-	_, err = db.Do("RPUSH", m.getCoreXQueue(args.Container), string(data))
+	db := m.pool.Get()
+	defer db.Close()
+
+	_, err := db.Do("RPUSH", m.getCoreXQueue(args.Container), string(data))
 
-	return id, err
+	return args.Command.ID, err
 }
 
 type ContainerTerminateArguments struct {
 	Container uint64 `json:"container"`
 }
 
-func (m *containerManager) terminate(cmd *core.Command) (interface{}, error) {
+func (m *containerManager) terminate(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	var args ContainerTerminateArguments
 	if err := json.Unmarshal(*cmd.Arguments, &args); err != nil {
 		return nil, err
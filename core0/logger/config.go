@@ -65,9 +65,17 @@ func InitLogging() {
 
 			loggers = append(loggers, handler)
 			registerGetMsgsFunction(db)
+
+			store, err := logger.NewResultStore(db, time.Duration(logcfg.ResultRetention)*time.Hour)
+			if err != nil {
+				log.Fatalf("Failed to initialize job results store: %s", err)
+			}
+
+			pm.GetManager().AddResultHandler(store.Store)
+			registerJobFunctions(store)
 			dbLoggerConfigured = true
 		case "redis":
-			handler := logger.NewRedisLogger(0, logcfg.Address, "", logcfg.Levels, logcfg.BatchSize)
+			handler := logger.NewRedisLogger(0, logcfg.Address, "", logcfg.Security.Files(), logcfg.Levels, logcfg.BatchSize)
 			loggers = append(loggers, handler)
 		case "console":
 			handler := logger.NewConsoleLogger(0, logcfg.Levels)
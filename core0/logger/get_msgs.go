@@ -1,13 +1,13 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/g8os/core0/base/pm"
 	"github.com/g8os/core0/base/pm/core"
-	"github.com/g8os/core0/base/pm/process"
 	"github.com/g8os/core0/base/utils"
 )
 
@@ -58,7 +58,7 @@ func getLevels(levels interface{}) ([]int, error) {
 	return results, nil
 }
 
-func (fnc *getMsgsFunc) getMsgs(cmd *core.Command) (interface{}, error) {
+func (fnc *getMsgsFunc) getMsgs(ctx context.Context, cmd *core.Command) (interface{}, error) {
 	query := logQuery{}
 
 	err := json.Unmarshal(*cmd.Arguments, &query)
@@ -125,5 +125,5 @@ func registerGetMsgsFunction(db *bolt.DB) {
 		db: db,
 	}
 
-	pm.CmdMap[cmdGetMsgs] = process.NewInternalProcessFactory(fnc.getMsgs)
+	pm.RegisterBuiltIn(cmdGetMsgs, fnc.getMsgs)
 }
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/g8os/core0/base/logger"
+	"github.com/g8os/core0/base/pm"
+	"github.com/g8os/core0/base/pm/core"
+)
+
+const (
+	cmdJobResult              = "job.result"
+	cmdJobHistory             = "job.history"
+	cmdJobHistoryDefaultLimit = 100
+)
+
+type jobResultQuery struct {
+	ID string `json:"id"`
+}
+
+type jobFunctions struct {
+	store *logger.ResultStore
+}
+
+func (fnc *jobFunctions) result(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	var query jobResultQuery
+	if err := json.Unmarshal(*cmd.Arguments, &query); err != nil {
+		return nil, err
+	}
+
+	if query.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	return fnc.store.Get(query.ID)
+}
+
+func (fnc *jobFunctions) history(ctx context.Context, cmd *core.Command) (interface{}, error) {
+	var query logger.ResultQuery
+	if err := json.Unmarshal(*cmd.Arguments, &query); err != nil {
+		return nil, err
+	}
+
+	if query.Limit <= 0 || query.Limit > cmdJobHistoryDefaultLimit {
+		query.Limit = cmdJobHistoryDefaultLimit
+	}
+
+	return fnc.store.Query(&query)
+}
+
+func registerJobFunctions(store *logger.ResultStore) {
+	fnc := &jobFunctions{
+		store: store,
+	}
+
+	pm.RegisterBuiltIn(cmdJobResult, fnc.result)
+	pm.RegisterBuiltIn(cmdJobHistory, fnc.history)
+}
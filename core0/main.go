@@ -72,6 +72,13 @@ func main() {
 	log.Infof("Starting process manager")
 	mgr := pm.GetManager()
 
+	if capture := config.Capture; capture.Directory != "" {
+		if err := mgr.CaptureOutput(capture.Directory, capture.MaxSize, capture.MaxFiles,
+			time.Duration(capture.Retention)*time.Hour); err != nil {
+			log.Errorf("Failed to enable output capture: %s", err)
+		}
+	}
+
 	mgr.AddResultHandler(func(cmd *pmcore.Command, result *pmcore.JobResult) {
 		log.Infof("Job result for command '%s' is '%s'", cmd, result.State)
 	})
@@ -109,11 +116,12 @@ func main() {
 		}
 
 		sinks[key] = cl
+		mgr.SetRouteMaxJobs(pmcore.Route(key), sinkCfg.MaxJobs)
 	}
 
 	log.Infof("Setting up stats buffers")
 	if config.Stats.Redis.Enabled {
-		redis := core.NewRedisStatsBuffer(config.Stats.Redis.Address, "", 1000, time.Duration(config.Stats.Redis.FlushInterval)*time.Millisecond)
+		redis := core.NewRedisStatsBuffer(config.Stats.Redis.Address, "", config.Stats.Redis.Security.Files(), 1000, time.Duration(config.Stats.Redis.FlushInterval)*time.Millisecond)
 		mgr.AddStatsFlushHandler(redis.Handler)
 	}
 
@@ -122,9 +130,17 @@ func main() {
 		log.Errorf("failed to intialize container subsystem", err)
 	}
 
+	var verifier *core.Verifier
+	if config.Signature.Required || len(config.Signature.Keys) > 0 {
+		verifier, err = core.NewVerifier(&config.Signature)
+		if err != nil {
+			log.Fatalf("Invalid signature settings: %s", err)
+		}
+	}
+
 	//start jobs sinks.
 	log.Infof("Starting Sinks")
-	core.StartSinks(pm.GetManager(), sinks)
+	core.StartSinks(pm.GetManager(), sinks, verifier)
 
 	//wait
 	select {}
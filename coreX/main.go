@@ -9,6 +9,7 @@ import (
 	"github.com/g8os/core0/base/pm"
 	pmcore "github.com/g8os/core0/base/pm/core"
 	"github.com/g8os/core0/base/settings"
+	"github.com/g8os/core0/base/utils"
 	"github.com/g8os/core0/coreX/bootstrap"
 	"github.com/g8os/core0/coreX/options"
 	"github.com/op/go-logging"
@@ -81,7 +82,7 @@ func main() {
 	}
 
 	log.Infof("Configure redis logger")
-	rl := logger.NewRedisLogger(uint16(opt.CoreID()), opt.RedisSocket(), "", nil, 100000)
+	rl := logger.NewRedisLogger(uint16(opt.CoreID()), opt.RedisSocket(), "", utils.TLSFiles{}, nil, 100000)
 	mgr.AddMessageHandler(rl.Log)
 	//
 	//log.Infof("Setting up stats buffers")
@@ -90,8 +91,24 @@ func main() {
 	//	mgr.AddStatsFlushHandler(redis.Handler)
 	//}
 
+	//the redis socket is shared by all the containers, so the commands are verified like on core0. The signatures
+	//must name the container id as node.
+	var verifier *core.Verifier
+	if keys := opt.TrustedKeys(); opt.SignatureRequired() || len(keys) > 0 {
+		verifier, err = core.NewVerifier(&settings.Signature{
+			Required: opt.SignatureRequired(),
+			Window:   opt.SignatureWindow(),
+			Keys:     keys,
+			Node:     sinkID,
+		})
+
+		if err != nil {
+			log.Fatalf("Invalid signature options: %s", err)
+		}
+	}
+
 	//start jobs sinks.
-	core.StartSinks(pm.GetManager(), sinks)
+	core.StartSinks(pm.GetManager(), sinks, verifier)
 
 	//wait
 	select {}
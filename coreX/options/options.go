@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 type AppOptions struct {
@@ -13,6 +14,9 @@ type AppOptions struct {
 	replyTo       string
 	maxJobs       int
 	hostname      string
+	trustedKeys   string
+	sigRequired   bool
+	sigWindow     int
 }
 
 func (o *AppOptions) CoreID() uint64 {
@@ -39,6 +43,26 @@ func (o *AppOptions) Hostname() string {
 	return o.hostname
 }
 
+//TrustedKeys gets the trusted public keys by key id
+func (o *AppOptions) TrustedKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(o.trustedKeys, ",") {
+		if parts := strings.SplitN(strings.TrimSpace(pair), "=", 2); len(parts) == 2 {
+			keys[parts[0]] = parts[1]
+		}
+	}
+
+	return keys
+}
+
+func (o *AppOptions) SignatureRequired() bool {
+	return o.sigRequired
+}
+
+func (o *AppOptions) SignatureWindow() int {
+	return o.sigWindow
+}
+
 func (o *AppOptions) Validate() []error {
 	errors := make([]error, 0)
 	if o.coreID == 0 {
@@ -63,6 +87,9 @@ func init() {
 	flag.StringVar(&Options.replyTo, "reply-to", "corex:results", "Reply to queue")
 	flag.IntVar(&Options.maxJobs, "max-jobs", 100, "Max number of jobs that can run concurrently")
 	flag.StringVar(&Options.hostname, "hostname", "", "Hostname of the container")
+	flag.StringVar(&Options.trustedKeys, "trusted-keys", "", "Trusted ed25519 public keys (key-id=base64,...) [optional]")
+	flag.BoolVar(&Options.sigRequired, "signature-required", false, "Reject the commands that are not signed")
+	flag.IntVar(&Options.sigWindow, "signature-window", 0, "Seconds a signature is valid around its timestamp [optional]")
 
 	flag.Parse()
 